@@ -0,0 +1,202 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// path of the ALPS profile below the server prefix, with the profile enabled
+// every decorated response links to it with the profile relation
+const ProfilePath = "profile"
+
+type AlpsProfile struct {
+	Alps		Alps			`json:"alps"`
+}
+
+type Alps struct {
+	Version		string			`json:"version" xml:"version,attr"`
+	Doc		*AlpsDoc		`json:"doc,omitempty" xml:"doc,omitempty"`
+	Descriptors	[]AlpsDescriptor	`json:"descriptor" xml:"descriptor"`
+}
+
+type AlpsDescriptor struct {
+	Id		string			`json:"id" xml:"id,attr"`
+	Type		string			`json:"type,omitempty" xml:"type,attr,omitempty"`
+	Name		string			`json:"name,omitempty" xml:"name,attr,omitempty"`
+	Rt		string			`json:"rt,omitempty" xml:"rt,attr,omitempty"`
+	Href		string			`json:"href,omitempty" xml:"href,attr,omitempty"`
+	Doc		*AlpsDoc		`json:"doc,omitempty" xml:"doc,omitempty"`
+	Descriptors	[]AlpsDescriptor	`json:"descriptor,omitempty" xml:"descriptor"`
+}
+
+type AlpsDoc struct {
+	Value		string			`json:"value" xml:",chardata"`
+}
+
+// Walks the registered entities and describes their properties as semantic
+// descriptors and their links and actions as transitions
+// mime type: application/alps+json, application/alps+xml
+func (this *Decorator) Profile() AlpsProfile {
+	var profile	AlpsProfile
+
+	profile.Alps.Version = "1.0"
+	profile.Alps.Descriptors = make([]AlpsDescriptor, 0)
+
+	snap := this.registered()
+	used := make(map[string]bool)
+
+	classes := make([]string, 0, len(snap.entities))
+	for class := range snap.entities {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	for _, class := range classes {
+		ent := snap.entities[class]
+
+		desc := AlpsDescriptor{Id: alpsId(used, class), Type: "semantic"}
+		if ent.title != "" {
+			desc.Doc = &AlpsDoc{ent.title}
+		}
+
+		// properties come from the struct registered for the entity, or
+		// named by the properties of its ClassDef
+		if t, found := snap.requests[ent.props]; found {
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				if f.PkgPath != "" || isHypermediaField(f.Type) {
+					continue
+				}
+				desc.Descriptors = append(desc.Descriptors, AlpsDescriptor{Id: alpsId(used, class + "." + f.Name), Name: f.Name, Type: "semantic"})
+			}
+		}
+
+		// links sharing a rel are one transition
+		rels := make(map[string]bool)
+		for j := 0; j < len(ent.links); j++ {
+			e_lnk := ent.links[j]
+			for _, rel := range splitList(e_lnk.rel) {
+				if rels[rel] {
+					continue
+				}
+				rels[rel] = true
+
+				lnk := AlpsDescriptor{Id: alpsId(used, class + "." + rel), Name: rel, Type: "safe"}
+				if e_lnk.title != "" {
+					lnk.Doc = &AlpsDoc{e_lnk.title}
+				}
+				desc.Descriptors = append(desc.Descriptors, lnk)
+			}
+		}
+
+		for j := 0; j < len(ent.actions); j++ {
+			e_act := ent.actions[j]
+			act := AlpsDescriptor{Id: alpsId(used, class + "." + e_act.name), Name: e_act.name, Type: alpsType(e_act.method)}
+			if e_act.returns != "" {
+				act.Rt = "#" + e_act.returns
+			}
+			if e_act.title != "" {
+				act.Doc = &AlpsDoc{e_act.title}
+			}
+			if e_act.fields != "" {
+				for _, fld := range this.requestFields(e_act.fields) {
+					act.Descriptors = append(act.Descriptors, AlpsDescriptor{Id: alpsId(used, act.Id + "." + fld.name), Name: fld.name, Type: "semantic"})
+				}
+			}
+			desc.Descriptors = append(desc.Descriptors, act)
+		}
+
+		profile.Alps.Descriptors = append(profile.Alps.Descriptors, desc)
+	}
+
+	return profile
+}
+
+// descriptor ids are unique within the profile, a taken id gets a number
+func alpsId(used map[string]bool, id string) string {
+	unique := id
+	for i := 2; used[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// GET is safe, PUT and DELETE are idempotent, anything else is unsafe
+func alpsType(method string) string {
+	switch strings.ToUpper(method) {
+		case "GET", "HEAD", "":
+			return "safe"
+		case "PUT", "DELETE":
+			return "idempotent"
+	}
+	return "unsafe"
+}
+
+// alps xml - <alps version="1.0"><descriptor .../></alps>
+func (this AlpsProfile) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "alps"}}
+	return e.EncodeElement(this.Alps, start)
+}
+
+// Links decorated responses to the ALPS profile, the application serves it
+// at ProfilePath below the prefix, e.g. with ProfileHandler
+func (this *Decorator) EnableProfile() {
+	this.reg.update(func(snap *snapshot) {
+		snap.profile = true
+	})
+}
+
+// Serves the ALPS profile as application/alps+xml when the client asks for
+// it, as application/alps+json otherwise
+func (this *Decorator) ProfileHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		profile := this.Profile()
+		if strings.Contains(r.Header.Get("Accept"), "application/alps+xml") {
+			w.Header().Set("Content-Type", "application/alps+xml")
+			xml.NewEncoder(w).Encode(profile)
+			return
+		}
+		w.Header().Set("Content-Type", "application/alps+json")
+		json.NewEncoder(w).Encode(profile)
+	})
+}
+
+// href of the profile link added to decorated responses, empty while the
+// profile is not enabled
+func (this *Decorator) profileHref() string {
+	if !this.registered().profile {
+		return ""
+	}
+	return this.UpdatePath(ProfilePath, nil)
+}
@@ -0,0 +1,335 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"crypto/sha1"
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// URL namespace of RFC 4122, ids of resources without an absolute href are
+// name based uuids below it
+var atomIdNamespace = []byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+type AtomFeed struct {
+	XMLName		xml.Name	`xml:"http://www.w3.org/2005/Atom feed"`
+	Id		string		`xml:"id"`
+	Title		string		`xml:"title"`
+	Updated		string		`xml:"updated"`
+	Author		*AtomPerson	`xml:"author,omitempty"`
+	Links		[]AtomLink	`xml:"link"`
+	Collection	*AppCollection	`xml:"http://www.w3.org/2007/app collection,omitempty"`
+	Entries		[]AtomEntry	`xml:"entry"`
+}
+
+type AtomEntry struct {
+	XMLName		xml.Name	`xml:"http://www.w3.org/2005/Atom entry"`
+	Id		string		`xml:"id"`
+	Title		string		`xml:"title"`
+	Updated		string		`xml:"updated"`
+	Author		*AtomPerson	`xml:"author,omitempty"`
+	Links		[]AtomLink	`xml:"link"`
+	Content		AtomContent	`xml:"content"`
+}
+
+type AtomLink struct {
+	Rel		string		`xml:"rel,attr,omitempty"`
+	Href		string		`xml:"href,attr"`
+	Type		string		`xml:"type,attr,omitempty"`
+	Title		string		`xml:"title,attr,omitempty"`
+}
+
+type AtomContent struct {
+	Type		string		`xml:"type,attr"`
+	Properties	[]AtomProperty	`xml:"properties>property"`
+}
+
+type AtomPerson struct {
+	Name		string		`xml:"name"`
+}
+
+// lists, maps and structs nest a property for each item
+type AtomProperty struct {
+	Name		string		`xml:"name,attr,omitempty"`
+	Value		string		`xml:",chardata"`
+	Properties	[]AtomProperty	`xml:"property"`
+}
+
+type AppCollection struct {
+	Href		string		`xml:"href,attr"`
+	Title		string		`xml:"http://www.w3.org/2005/Atom title"`
+	Accept		string		`xml:"accept,omitempty"`
+}
+
+// This takes the data destined for the http response body and renders a
+// list as an Atom feed with an entry per item, a single struct as an entry
+// mime type: application/atom+xml
+func atomDecorator(response interface{}, dec *Decorator) (interface{}) {
+	updated := time.Now().UTC().Format(time.RFC3339)
+
+	v := reflect.ValueOf(response)
+	switch v.Kind() {
+		case reflect.Struct:
+			entry := getAtomEntry(dec, v, updated)
+			entry.Author = &AtomPerson{dec.author()}
+			if profile := dec.profileHref(); profile != "" {
+				entry.Links = append(entry.Links, AtomLink{Rel: "profile", Href: profile})
+			}
+			return entry
+		case reflect.Slice, reflect.Array:
+			var hm_resp	AtomFeed
+
+			class := v.Type().Elem().Name()
+			ent := dec.GetEntity("[]" + class)
+			props := make(map[string]interface{})
+
+			hm_resp.Title = class
+			hm_resp.Updated = updated
+			hm_resp.Author = &AtomPerson{dec.author()}
+			href := ""
+			if ent != nil {
+				if ent.title != "" {
+					hm_resp.Title = ent.title
+				}
+				if ent.href != "" {
+					href = dec.UpdatePath(ent.href, props)
+				}
+			}
+			hm_resp.Id = atomId(href, "[]" + class)
+			hm_resp.Links = atomLinks(dec, ent, props, href)
+			if profile := dec.profileHref(); profile != "" {
+				hm_resp.Links = append(hm_resp.Links, AtomLink{Rel: "profile", Href: profile})
+			}
+
+			// posting to the list's href (or the item's create action) adds an entry
+			if hm_resp.Collection = appCollection(dec, ent, props); hm_resp.Collection == nil {
+				hm_resp.Collection = appCollection(dec, dec.GetEntity(class), props)
+			}
+
+			for i := 0; i < v.Len(); i++ {
+				hm_resp.Entries = append(hm_resp.Entries, getAtomEntry(dec, v.Index(i), updated))
+			}
+			return hm_resp
+	}
+
+	return response
+}
+
+func getAtomEntry(dec *Decorator, vItem reflect.Value, updated string) AtomEntry {
+	var entry	AtomEntry
+
+	entry.Title = vItem.Type().Name()
+	entry.Updated = updated
+	entry.Content.Type = "application/xml"
+
+	props := make(map[string]interface{})
+	if vItem.Kind() == reflect.Struct {
+		typ := vItem.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if f.PkgPath != "" || isHypermediaField(f.Type) {
+				continue
+			}
+			props[f.Name] = vItem.Field(i).Interface()
+			entry.Content.Properties = append(entry.Content.Properties, atomProperty(f.Name, vItem.Field(i).Interface()))
+		}
+	} else {
+		entry.Content.Properties = append(entry.Content.Properties, atomProperty(entry.Title, vItem.Interface()))
+	}
+
+	ent := dec.GetEntity(vItem.Type().Name())
+	name, href := entry.Title, ""
+	if typ, id := resourceIdent(ent, vItem); id != "" {
+		name = typ + ":" + id
+	}
+	if ent != nil {
+		if ent.title != "" {
+			entry.Title = ent.title
+		}
+		if ent.href != "" {
+			href = dec.UpdatePath(ent.href, props)
+		}
+	}
+	entry.Id = atomId(href, name)
+	entry.Links = atomLinks(dec, ent, props, href)
+
+	return entry
+}
+
+// Sets the author Atom requires on feeds and stand-alone entries
+func (this *Decorator) SetAuthor(name string) {
+	this.reg.update(func(snap *snapshot) {
+		snap.author = name
+	})
+}
+
+// the configured author, anonymous when none is set
+func (this *Decorator) author() string {
+	if author := this.registered().author; author != "" {
+		return author
+	}
+	return "anonymous"
+}
+
+// the href when it is an absolute IRI, otherwise a name based uuid urn
+func atomId(href string, name string) string {
+	if strings.Contains(href, "://") {
+		return href
+	}
+	if href != "" {
+		name = href
+	}
+
+	hash := sha1.New()
+	hash.Write(atomIdNamespace)
+	hash.Write([]byte(name))
+	uuid := hash.Sum(nil)[:16]
+	uuid[6] = (uuid[6] & 0x0f) | 0x50
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+// scalar values are the text of the property, lists, maps and structs nest
+// a property for each item
+func atomProperty(name string, value interface{}) AtomProperty {
+	prop := AtomProperty{Name: name}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return prop
+		}
+		v = v.Elem()
+	}
+
+	if _, ok := value.(encoding.TextMarshaler); ok {
+		prop.Value = valueText(value)
+		return prop
+	}
+
+	switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				break
+			}
+			for i := 0; i < v.Len(); i++ {
+				prop.Properties = append(prop.Properties, atomProperty("", v.Index(i).Interface()))
+			}
+			return prop
+		case reflect.Map:
+			items := make(map[string]interface{}, v.Len())
+			for _, key := range v.MapKeys() {
+				items[fmt.Sprint(key.Interface())] = v.MapIndex(key).Interface()
+			}
+			for _, key := range sortedKeys(items) {
+				prop.Properties = append(prop.Properties, atomProperty(key, items[key]))
+			}
+			return prop
+		case reflect.Struct:
+			typ := v.Type()
+			for i := 0; i < typ.NumField(); i++ {
+				f := typ.Field(i)
+				if f.PkgPath != "" || isHypermediaField(f.Type) {
+					continue
+				}
+				prop.Properties = append(prop.Properties, atomProperty(f.Name, v.Field(i).Interface()))
+			}
+			return prop
+		case reflect.Invalid:
+			return prop
+	}
+
+	prop.Value = valueText(v.Interface())
+	return prop
+}
+
+// registered links by rel, self defaults to the entity href and the first
+// PUT action is the edit link
+func atomLinks(dec *Decorator, ent *entity, props map[string]interface{}, self string) []AtomLink {
+	lnklist := make([]AtomLink, 0)
+
+	if ent == nil {
+		return lnklist
+	}
+
+	hasSelf := false
+	for j := 0; j < len(ent.links); j++ {
+		e_lnk := ent.links[j]
+		if !dec.hasAccess(e_lnk.href, "GET") {
+			continue
+		}
+
+		for _, rel := range splitList(e_lnk.rel) {
+			lnklist = append(lnklist, AtomLink{rel, dec.UpdatePath(e_lnk.href, props), e_lnk.typ, e_lnk.title})
+			hasSelf = hasSelf || rel == "self"
+		}
+	}
+
+	if !hasSelf && ent.href != "" && dec.hasAccess(ent.href, "GET") {
+		lnklist = append(lnklist, AtomLink{"self", self, ent.typ, ""})
+	}
+
+	for j := 0; j < len(ent.actions); j++ {
+		e_act := ent.actions[j]
+		if e_act.method == "PUT" && dec.hasAccess(e_act.href, e_act.method) {
+			lnklist = append(lnklist, AtomLink{"edit", dec.UpdatePath(e_act.href, props), e_act.typ, e_act.title})
+			break
+		}
+	}
+	return lnklist
+}
+
+func appCollection(dec *Decorator, ent *entity, props map[string]interface{}) *AppCollection {
+	if ent == nil {
+		return nil
+	}
+
+	for j := 0; j < len(ent.actions); j++ {
+		e_act := ent.actions[j]
+		if e_act.method == "POST" && dec.hasAccess(e_act.href, e_act.method) {
+			coll := AppCollection{dec.UpdatePath(e_act.href, props), e_act.title, e_act.typ}
+			if coll.Title == "" {
+				coll.Title = e_act.name
+			}
+			return &coll
+		}
+	}
+	return nil
+}
+
+// creates a new Atom Decorator
+func newAtomDecorator() *indivDec {
+	dec := new(indivDec)
+	dec.Decorate = atomDecorator
+	return dec
+}
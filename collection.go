@@ -0,0 +1,367 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+type CJDoc struct {
+	Collection	CJCollection	`json:"collection"`
+}
+
+type CJCollection struct {
+	Version		string		`json:"version"`
+	Href		string		`json:"href"`
+	Links		[]CJLink	`json:"links,omitempty"`
+	Items		[]CJItem	`json:"items,omitempty"`
+	Queries		[]CJQuery	`json:"queries,omitempty"`
+	Template	*CJTemplate	`json:"template,omitempty"`
+	Error		*CJError	`json:"error,omitempty"`
+}
+
+type CJLink struct {
+	Href		string		`json:"href"`
+	Rel		string		`json:"rel"`
+	Prompt		string		`json:"prompt,omitempty"`
+	Name		string		`json:"name,omitempty"`
+	Render		string		`json:"render,omitempty"`
+}
+
+type CJItem struct {
+	Href		string		`json:"href"`
+	Data		[]CJData	`json:"data,omitempty"`
+	Links		[]CJLink	`json:"links,omitempty"`
+}
+
+type CJQuery struct {
+	Href		string		`json:"href"`
+	Rel		string		`json:"rel"`
+	Prompt		string		`json:"prompt,omitempty"`
+	Name		string		`json:"name,omitempty"`
+	Data		[]CJData	`json:"data,omitempty"`
+}
+
+type CJTemplate struct {
+	Data		[]CJData	`json:"data,omitempty"`
+}
+
+type CJData struct {
+	Prompt		string		`json:"prompt,omitempty"`
+	Name		string		`json:"name"`
+	Value		interface{}	`json:"value,omitempty"`
+}
+
+type CJError struct {
+	Title		string		`json:"title,omitempty"`
+	Code		string		`json:"code,omitempty"`
+	Message		string		`json:"message,omitempty"`
+}
+
+// This takes the data destined for the http response body and adds hypermedia content
+// to the message prior to marshaling the data and returning it to the client
+// The CollectionDecorator loosely follows the collection+json specification
+// mime type: applcation/vnd.collection+json 
+func collectionDecorator(response interface{}, dec *Decorator) (interface{}) {
+	var hm_resp 	CJDoc
+
+	hm_resp.Collection.Version = "1.0"
+
+	if err, ok := response.(error); ok {
+		hm_resp.Collection.Error = collectionError(err)
+		return hm_resp
+	}
+
+	v := reflect.ValueOf(response)
+	switch v.Kind() {
+		case reflect.Struct:
+			// Properties - data of the single item
+			// Any sub-entities (struct or array), placed in Items after it
+			class := reflect.TypeOf(response).Name()
+			props, data, items := stripSubItems(dec, v)
+
+			ent := dec.GetEntity(class)
+			var item	CJItem
+			item.Href = collectionHref(dec, ent, props)
+			item.Data = data
+			item.Links = collectionLinks(dec, ent, props)
+
+			hm_resp.Collection.Href = item.Href
+			hm_resp.Collection.Items = append([]CJItem{item}, items...)
+			hm_resp.Collection.Queries = collectionQueries(dec, ent, props)
+			hm_resp.Collection.Template = collectionTemplate(dec, ent)
+		case reflect.Slice, reflect.Array:
+			props := make(map[string]interface{})
+			items, class := getItemList(dec, v)
+
+			ent := dec.GetEntity(class)
+			hm_resp.Collection.Href = collectionHref(dec, ent, props)
+			hm_resp.Collection.Links = collectionLinks(dec, ent, props)
+			hm_resp.Collection.Items = items
+
+			// forms for a list fall back to the actions of the list item
+			if ent == nil && v.Len() > 0 {
+				ent = dec.GetEntity(v.Index(0).Type().Name())
+			}
+			hm_resp.Collection.Queries = collectionQueries(dec, ent, props)
+			hm_resp.Collection.Template = collectionTemplate(dec, ent)
+		case reflect.Invalid:
+			// no body, e.g. 204 No Content
+		default:
+			var item	CJItem
+			item.Data = []CJData{CJData{"", reflect.TypeOf(response).Name(), response}}
+			hm_resp.Collection.Items = []CJItem{item}
+	}
+
+	if profile := dec.profileHref(); profile != "" {
+		hm_resp.Collection.Links = append(hm_resp.Collection.Links, CJLink{profile, "profile", "", "", "link"})
+	}
+
+	return hm_resp
+}
+
+func collectionError(err error) *CJError {
+	hmErr := toHypermediaError(err)
+	return &CJError{hmErr.Title, hmErr.Code, hmErr.Message}
+}
+
+func collectionHref(dec *Decorator, ent *entity, props map[string]interface{}) string {
+	if ent == nil || ent.href == "" {
+		return ""
+	}
+	return dec.UpdatePath(ent.href, props)
+}
+
+func collectionLinks(dec *Decorator, ent *entity, props map[string]interface{}) []CJLink {
+	lnklist := make([]CJLink, 0)
+
+	if ent != nil {
+		for _, e_lnk := range ent.links {
+			if dec.hasAccess(e_lnk.href, "GET") {
+				lnk := CJLink{e_lnk.href, strings.Join(splitList(e_lnk.rel), " "), e_lnk.title, e_lnk.name, "link"}
+				lnk.Href = dec.UpdatePath(lnk.Href, props)
+				lnklist = append(lnklist, lnk)
+			}
+		}
+	}
+	return lnklist
+}
+
+// GET actions with parameters not supplied by the properties become queries,
+// parameters are taken from {name} and {?name,...} segments of the href
+func collectionQueries(dec *Decorator, ent *entity, props map[string]interface{}) []CJQuery {
+	qrylist := make([]CJQuery, 0)
+
+	if ent == nil {
+		return qrylist
+	}
+
+	reg := regexp.MustCompile("{[^}]+}")
+	for j := 0; j < len(ent.actions); j++ {
+		e_act := ent.actions[j]
+		if e_act.method != "GET" || !dec.hasAccess(e_act.href, e_act.method) {
+			continue
+		}
+
+		href := e_act.href
+		data := make([]CJData, 0)
+		for _, part := range reg.FindAllString(href, -1) {
+			names := part[1:len(part) - 1]
+			if strings.HasPrefix(names, "?") || strings.HasPrefix(names, "&") {
+				names = names[1:]
+				href = strings.Replace(href, part, "", 1)
+			} else if _, found := props[names]; found {
+				continue
+			}
+
+			for _, name := range strings.Split(names, ",") {
+				data = append(data, CJData{"", name, ""})
+			}
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+
+		if pos := strings.Index(href, "?"); pos > -1 {
+			href = href[:pos]
+		}
+
+		rel := e_act.class
+		if rel == "" {
+			rel = "search"
+		}
+
+		qry := CJQuery{dec.UpdatePath(href, props), rel, e_act.title, e_act.name, data}
+		qrylist = append(qrylist, qry)
+	}
+	return qrylist
+}
+
+// the write template is built from the request struct of the first POST
+// action, or of the first PUT action when the class cannot be created
+func collectionTemplate(dec *Decorator, ent *entity) *CJTemplate {
+	if ent == nil {
+		return nil
+	}
+
+	var tmpl_act	*action
+	for j := 0; j < len(ent.actions); j++ {
+		e_act := ent.actions[j]
+		if e_act.fields == "" || !dec.hasAccess(e_act.href, e_act.method) {
+			continue
+		}
+		if e_act.method == "POST" {
+			tmpl_act = &e_act
+			break
+		} else if e_act.method == "PUT" && tmpl_act == nil {
+			tmpl_act = &e_act
+		}
+	}
+
+	if tmpl_act == nil {
+		return nil
+	}
+
+	tmpl := new(CJTemplate)
+	for _, fld := range dec.requestFields(tmpl_act.fields) {
+		tmpl.Data = append(tmpl.Data, CJData{fld.title, fld.name, fld.value})
+	}
+	return tmpl
+}
+
+func stripSubItems(dec *Decorator, in reflect.Value) (map[string]interface{}, []CJData, []CJItem) {
+	items := []CJItem{}
+	data := []CJData{}
+	out := make(map[string]interface{}, 30)
+
+	typ := reflect.TypeOf(in.Interface())
+	for i := 0; i < typ.NumField(); i++ {
+		vItem := in.Field(i)
+		name := typ.Field(i).Name
+		if typ.Field(i).PkgPath != "" || isHypermediaField(typ.Field(i).Type) {
+			continue
+		}
+		switch vItem.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				if vItem.Len() > 0 && vItem.Kind() != reflect.Map {
+					item := vItem.Index(0)
+					if itm := dec.GetEntity(item.Type().Name()); itm != nil {
+						tmp, _ := getItemList(dec, vItem)
+						items = append(items, tmp...)
+						continue
+					}
+				}
+			default:
+				if itm := dec.GetEntity(vItem.Type().Name()); itm != nil && vItem.Kind() == reflect.Struct {
+					item := getItem(dec, vItem, "class")
+					items = append(items, item)
+					continue
+				}
+		}
+		out[name] = vItem.Interface()
+		data = append(data, CJData{"", name, vItem.Interface()})
+	}
+
+	return out, data, items
+}
+
+func getItemList(dec *Decorator, val reflect.Value) ([]CJItem, string) {
+	itemList := []CJItem{}
+	var className string
+
+	for i := 0; i < val.Len(); i++ {
+		vItem := val.Index(i)
+
+		item := getItem(dec, vItem, "list")
+
+		itemList = append(itemList, item)
+
+		if i == 0 {
+			className = "[]" + vItem.Type().Name()
+		}
+	}
+
+	return itemList, className
+}
+
+func getItem(dec *Decorator, vItem reflect.Value, colType string) CJItem {
+	var item	CJItem
+
+	props := make(map[string]interface{})
+	if vItem.Kind() == reflect.Struct {
+		typ := vItem.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			valf := vItem.Field(i)
+			if typ.Field(i).PkgPath != "" {
+				continue
+			}
+			props[typ.Field(i).Name] = valf.Interface()
+			if isHypermediaField(typ.Field(i).Type) {
+				continue
+			}
+			item.Data = append(item.Data, CJData{"", typ.Field(i).Name, valf.Interface()})
+		}
+	} else {
+		item.Data = append(item.Data, CJData{"", vItem.Type().Name(), vItem.Interface()})
+	}
+
+	if subent := dec.GetEntity(vItem.Type().Name()); subent != nil {
+		item.Href = collectionHref(dec, subent, props)
+
+		for j:= 0; j < len(subent.links); j++ {
+			process := false
+			if (subent.links[j].in == "both" || subent.links[j].in == "list") && colType == "list" {
+				process = true
+			}
+
+			if (subent.links[j].in == "both" || subent.links[j].in == "class") && colType == "class" {
+				process = true
+			}
+
+			if process {
+				if dec.hasAccess(subent.links[j].href, "GET") {
+					lnk := CJLink{subent.links[j].href, strings.Join(splitList(subent.links[j].rel), " "), subent.links[j].title, subent.links[j].name, "link"}
+
+					lnk.Href = dec.UpdatePath(lnk.Href, props)
+
+					item.Links = append(item.Links, lnk)
+				}
+			}
+		}
+	}
+	return item
+}
+
+// creates a new Collection Decorator 
+func newCollectionDecorator() *indivDec {
+	dec := new(indivDec)
+	dec.Decorate = collectionDecorator
+	return dec
+}
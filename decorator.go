@@ -27,6 +27,8 @@
 package hypermedia
 
 import (
+	"encoding"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -45,8 +47,13 @@ type ResourceDef struct {
 
 type ClassDef struct {
 	ResourceName    string                  `json:"resource"`
+	RelName		string			`json:"rel"`
+	IdField		string			`json:"id"`
+	Title		string			`json:"title"`
+	Properties	string			`json:"properties"`
 	Actions         []ActionDef             `json:"actions"`
 	Links           []LinkDef               `json:"links"`
+	EmbedLinks	[]string		`json:"embedLinks"`
 }
 
 type ActionDef struct {
@@ -54,14 +61,26 @@ type ActionDef struct {
 	Class           string                  `json:"class"`
 	Method          string                  `json:"method"`
 	Href            string                  `json:"href"`
+	Title		string			`json:"title"`
+	Type		string			`json:"type"`
 	In		string			`json:"in"`
+	Fields		string			`json:"fields"`
+	Returns		string			`json:"returns"`
 }
 
 type LinkDef struct {
 	Name            string                  `json:"name"`
+	Rel		string			`json:"rel"`
 	Class           string                  `json:"class"`
 	Href            string                  `json:"href"`
+	Title		string			`json:"title"`
+	Type		string			`json:"type"`
 	In		string			`json:"in"`
+	Array		bool			`json:"array"`
+	Templated	bool			`json:"templated"`
+	Deprecation	string			`json:"deprecation"`
+	Profile		string			`json:"profile"`
+	Hreflang	string			`json:"hreflang"`
 }
 
 // siren - entity, hal - resource and embedded
@@ -70,9 +89,14 @@ type entity struct {
 	title		string
 	typ		string
 	href		string
+	rel		string
+	idField		string
+	props		string
 	links		map[int]link
 	actions		map[int]action
 	curies		map[int]curie
+	embedLinks	map[string]bool
+	embedRels	map[string]string
 }
 
 // all hypermedia formats
@@ -85,6 +109,10 @@ type link struct {
 	templated	bool
 	name		string
 	in		string
+	array		bool
+	deprecation	string
+	profile		string
+	hreflang	string
 }
 
 // siren - actions (leaving fields off for now)
@@ -96,6 +124,16 @@ type action struct {
 	title		string
 	typ		string
 	in		string
+	fields		string
+	returns		string
+}
+
+// request body field, used for templates and forms
+type formField struct {
+	name		string
+	typ		string
+	title		string
+	value		string
 }
 
 // hal - curie type, intended for documentation and URI prefix
@@ -115,12 +153,37 @@ type Curie bool			// hal
 //type Data bool			// collection+json
 
 type Decorator struct {
-	decorators		map[string]*indivDec
-	entities 		map[string]entity
+	reg			*registry
+	snap			*snapshot
 	scopes			map[string]bool
-	paths			map[string][]string
 	srvr_prefix		string
-	security_enabled	bool
+}
+
+// error carrying the details rendered into a hypermedia error document,
+// any other error is rendered with its message only
+type HypermediaError struct {
+	Title			string
+	Code			string
+	Message			string
+}
+
+func (this HypermediaError) Error() string {
+	if this.Code != "" {
+		return this.Code + ": " + this.Message
+	}
+	return this.Message
+}
+
+// any error as a HypermediaError, plain errors only carry a message
+func toHypermediaError(err error) HypermediaError {
+	var hmErr	HypermediaError
+	var hmErrPtr	*HypermediaError
+	if errors.As(err, &hmErr) {
+		return hmErr
+	} else if errors.As(err, &hmErrPtr) {
+		return *hmErrPtr
+	}
+	return HypermediaError{Message: err.Error()}
 }
 
 //Signiture of functions to be used as Decorators
@@ -128,26 +191,55 @@ type indivDec struct {
 	Decorate func(interface{}, *Decorator) (interface{})
 }
 
-func NewHypermediaDecorator() Decorator {
+// Creates a Decorator for all supported media types, configured by the options
+func NewDecorator(options ...Option) *Decorator {
 	dec := new(Decorator)
-	dec.decorators = make(map[string]*indivDec, 0)
+	dec.reg = newRegistry()
 
-	dec.security_enabled = false
 	dec.registerHypermedia("application/vnd.siren+json", newSirenDecorator())
+	dec.registerHypermedia("application/vnd.siren+xml", newSirenDecorator())
 	dec.registerHypermedia("application/hal+json", newHalDecorator())
-	dec.entities = make(map[string]entity)
-	dec.paths = make(map[string][]string)
+	dec.registerHypermedia("application/hal+xml", newHalDecorator())
+	dec.registerHypermedia("application/prs.hal-forms+json", newHalFormsDecorator())
+	dec.registerHypermedia("application/vnd.collection+json", newCollectionDecorator())
+	dec.registerHypermedia("application/vnd.api+json", newJsonApiDecorator())
+	dec.registerHypermedia("application/ld+json", newJsonLdDecorator())
+	dec.registerHypermedia("application/vnd.uber+json", newUberDecorator())
+	dec.registerHypermedia("application/vnd.uber+xml", newUberDecorator())
+	dec.registerHypermedia("application/vnd.mason+json", newMasonDecorator())
+	dec.registerHypermedia("text/html", newHtmlDecorator())
+	dec.registerHypermedia("application/atom+xml", newAtomDecorator())
+	dec.SetDefaultFormat("application/vnd.siren+json")
+
+	for _, option := range options {
+		option(dec)
+	}
+
+	return dec
+}
 
-	return *dec
+// Kept for compatibility, the returned value shares its registry and
+// configuration with any copy of it.  Use NewDecorator
+func NewHypermediaDecorator() Decorator {
+	return *NewDecorator()
 }
 
-func (this Decorator) Decorate(mime string, prefix string, response interface{}, scopes []string) (interface{}) {
+// Decorates the response for the mime type.  A copy of the decorator holds
+// the prefix, scopes and registry snapshot of this call, it is passed to the
+// renderer so that concurrent requests never share rendering state.  An
+// empty prefix falls back to the one set with SetPrefix
+func (this *Decorator) Decorate(mime string, prefix string, response interface{}, scopes []string) (interface{}) {
 	dec := this.getHypermedia(mime)
 	if dec == nil {
 		return response
 	} else {
-		this.srvr_prefix = prefix
-		this.scopes = make(map[string]bool)
+		call := *this
+		call.snap = this.reg.load()
+		call.srvr_prefix = prefix
+		if prefix == "" {
+			call.srvr_prefix = call.snap.prefix
+		}
+		call.scopes = make(map[string]bool)
 		for i := range scopes {
 			strScope := scopes[i]
 			hasContext := false
@@ -155,34 +247,55 @@ func (this Decorator) Decorate(mime string, prefix string, response interface{},
 				strScope = strScope[:pos]
 				hasContext = true
 			}
-			this.scopes[strScope] = hasContext
+			call.scopes[strScope] = hasContext
 		}
-		return dec.Decorate(response, &this)
+		return dec.Decorate(response, &call)
 	}
 }
 
 //Registers an Hypermedia Decorator for the specified mime type
-func (this Decorator) registerHypermedia(mime string, dec *indivDec) {
-	if _, found := this.decorators[mime]; !found {
-		this.decorators[mime] = dec
+func (this *Decorator) registerHypermedia(mime string, dec *indivDec) {
+	this.reg.update(func(snap *snapshot) {
+		if _, found := snap.decorators[mime]; !found {
+			snap.decorators[mime] = dec
+		}
+	})
+}
+
+// the snapshot of the current call, or the latest one outside of Decorate
+func (this *Decorator) registered() *snapshot {
+	if this.snap != nil {
+		return this.snap
 	}
+	return this.reg.load()
 }
 
 //Returns the registred decorator for the specified mime type
-func (this Decorator) getHypermedia(mime string) (dec *indivDec) {
-	dec, _ = this.decorators[mime]
+func (this *Decorator) getHypermedia(mime string) (dec *indivDec) {
+	dec, _ = this.registered().decorators[mime]
 	return
 }
 
-func (this Decorator) RegisterDefinition(hmDef HypermediaDef) {
+func (this *Decorator) RegisterDefinition(hmDef HypermediaDef) {
+	entities := make(map[string]entity)
+
 	for className, classData := range hmDef.Classes {
 		var ent		entity
 
 		ent.links = make(map[int]link)
 		ent.actions = make(map[int]action)
 		ent.curies = make(map[int]curie)
+		ent.embedLinks = make(map[string]bool)
+		ent.embedRels = make(map[string]string)
 
 		ent.class = className
+		ent.title = classData.Title
+		ent.rel = classData.RelName
+		ent.idField = classData.IdField
+		ent.props = className
+		if classData.Properties != "" {
+			ent.props = classData.Properties
+		}
 		ent.href = hmDef.Resources[classData.ResourceName].Href
 
 		for i := range classData.Actions {
@@ -192,7 +305,11 @@ func (this Decorator) RegisterDefinition(hmDef HypermediaDef) {
 			newAction.method = classData.Actions[i].Method
 			newAction.href = hmDef.Resources[classData.Actions[i].Class].Href + classData.Actions[i].Href
 			newAction.class = classData.Actions[i].Class
+			newAction.title = classData.Actions[i].Title
+			newAction.typ = classData.Actions[i].Type
 			newAction.in = classData.Actions[i].In
+			newAction.fields = classData.Actions[i].Fields
+			newAction.returns = classData.Actions[i].Returns
 
 			ent.actions[i] = newAction
 		}
@@ -202,18 +319,39 @@ func (this Decorator) RegisterDefinition(hmDef HypermediaDef) {
 
 			newLink.name = classData.Links[i].Name
 			newLink.rel = classData.Links[i].Name
+			if classData.Links[i].Rel != "" {
+				newLink.rel = classData.Links[i].Rel
+			}
 			newLink.href = hmDef.Resources[classData.Links[i].Class].Href + classData.Links[i].Href
 			newLink.class = classData.Links[i].Class
+			newLink.title = classData.Links[i].Title
+			newLink.typ = classData.Links[i].Type
 			newLink.in = classData.Links[i].In
+			newLink.array = classData.Links[i].Array
+			newLink.templated = classData.Links[i].Templated
+			newLink.deprecation = classData.Links[i].Deprecation
+			newLink.profile = classData.Links[i].Profile
+			newLink.hreflang = classData.Links[i].Hreflang
 
 			ent.links[i] = newLink
 		}
 
-		this.entities[className] = ent
+		for i := range classData.EmbedLinks {
+			ent.embedLinks[classData.EmbedLinks[i]] = true
+		}
+
+		entities[className] = ent
 	}
+
+	// the whole definition becomes visible at once
+	this.reg.update(func(snap *snapshot) {
+		for className, ent := range entities {
+			snap.entities[className] = ent
+		}
+	})
 }
  
-func (this Decorator) RegisterEntity(i_ent interface{}) {
+func (this *Decorator) RegisterEntity(i_ent interface{}) {
 	t := reflect.TypeOf(i_ent)
 
 	if t.Kind() == reflect.Ptr {
@@ -229,6 +367,9 @@ func (this Decorator) RegisterEntity(i_ent interface{}) {
 			ent.links = make(map[int]link)
 			ent.actions = make(map[int]action)
 			ent.curies = make(map[int]curie)
+			ent.embedLinks = make(map[string]bool)
+			ent.embedRels = make(map[string]string)
+			ent.props = t.Name()
 
 			linkcnt := 0
 			actioncnt := 0
@@ -248,15 +389,45 @@ func (this Decorator) RegisterEntity(i_ent interface{}) {
 					cur := prepCurieData(f.Name, reflect.StructTag(ftmp))
 					ent.curies[curiecnt] = cur
 					curiecnt++
+				} else {
+					if reflect.StructTag(ftmp).Get("embed") == "link" {
+						ent.embedLinks[f.Name] = true
+					}
+					if rel := reflect.StructTag(ftmp).Get("rel"); rel != "" {
+						ent.embedRels[f.Name] = rel
+					}
 				}
 			}
-			this.entities[ent.class] = ent	
+			this.reg.update(func(snap *snapshot) {
+				snap.entities[entityKey(ent.class)] = ent
+				snap.requests[t.Name()] = t
+			})
 		}
 	}
 }
 
-func (this Decorator) UnregisterEntity(classname string) {
-	delete(this.entities, classname)
+// Registers a struct describing a request body, referenced by name from
+// the fields tag of an Action or the fields property of an ActionDef
+func (this *Decorator) RegisterRequest(i_req interface{}) {
+	t := reflect.TypeOf(i_req)
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	} else {
+		panic("Invalid Interface")
+	}
+
+	if t.Kind() == reflect.Struct {
+		this.reg.update(func(snap *snapshot) {
+			snap.requests[t.Name()] = t
+		})
+	}
+}
+
+func (this *Decorator) UnregisterEntity(classname string) {
+	this.reg.update(func(snap *snapshot) {
+		delete(snap.entities, classname)
+	})
 }
 
 func prepEntityData(tags reflect.StructTag) entity {
@@ -280,9 +451,37 @@ func prepEntityData(tags reflect.StructTag) entity {
 		ent.typ = tag
 	}
 
+	if tag = tags.Get("rel"); tag != "" {
+		ent.rel = tag
+	}
+
+	if tag = tags.Get("id"); tag != "" {
+		ent.idField = tag
+	}
+
 	return *ent
 }
 
+// class and rel tags may hold a comma separated list, the first class
+// names the entity in the registry
+func splitList(tag string) []string {
+	var list	[]string
+
+	for _, itm := range strings.Split(tag, ",") {
+		if itm = strings.TrimSpace(itm); itm != "" {
+			list = append(list, itm)
+		}
+	}
+	return list
+}
+
+func entityKey(class string) string {
+	if list := splitList(class); len(list) > 0 {
+		return list[0]
+	}
+	return class
+}
+
 func prepLinkData(rel string, tags reflect.StructTag) link {
 	lnk := new(link)
 
@@ -290,6 +489,10 @@ func prepLinkData(rel string, tags reflect.StructTag) link {
 
 	lnk.rel = rel
 
+	if tag = tags.Get("rel"); tag != "" {
+		lnk.rel = tag
+	}
+
 	if tag = tags.Get("class"); tag != "" {
 		lnk.class = tag
 	}
@@ -306,6 +509,26 @@ func prepLinkData(rel string, tags reflect.StructTag) link {
 		lnk.typ = tag
 	}
 
+	if tag = tags.Get("array"); tag == "true" {
+		lnk.array = true
+	}
+
+	if tag = tags.Get("templated"); tag == "true" {
+		lnk.templated = true
+	}
+
+	if tag = tags.Get("deprecation"); tag != "" {
+		lnk.deprecation = tag
+	}
+
+	if tag = tags.Get("profile"); tag != "" {
+		lnk.profile = tag
+	}
+
+	if tag = tags.Get("hreflang"); tag != "" {
+		lnk.hreflang = tag
+	}
+
 	return *lnk
 }
 
@@ -336,6 +559,14 @@ func prepActionData(name string, tags reflect.StructTag) action {
 		act.typ = tag
 	}
 
+	if tag = tags.Get("fields"); tag != "" {
+		act.fields = tag
+	}
+
+	if tag = tags.Get("returns"); tag != "" {
+		act.returns = tag
+	}
+
 	return *act
 }
 
@@ -361,11 +592,133 @@ func prepCurieData(name string, tags reflect.StructTag) curie {
 	return *cur
 }
 
-func (this Decorator) EnableSecurity() {
-	this.security_enabled = true
+// the rel tag on the field takes precedence over the rel registered for the
+// embedded class, the go name is used when neither is set
+func embeddedRel(parent *entity, field string, ent *entity, name string) string {
+	if parent != nil {
+		if rel, found := parent.embedRels[field]; found {
+			return rel
+		}
+	}
+	if ent.rel != "" {
+		return ent.rel
+	}
+	return name
+}
+
+// the type of a resource is its registered class, the id is read from the
+// field named by the id tag, or the Id field when not tagged
+func resourceIdent(ent *entity, vItem reflect.Value) (string, string) {
+	typ := vItem.Type().Name()
+	if ent != nil {
+		typ = entityKey(ent.class)
+	}
+
+	idField := "Id"
+	if ent != nil && ent.idField != "" {
+		idField = ent.idField
+	}
+
+	id := ""
+	if vItem.Kind() == reflect.Struct {
+		if valf := vItem.FieldByName(idField); valf.IsValid() {
+			id = getValueString(valf.Interface())
+		}
+	}
+	return typ, id
+}
+
+// text of a value for xml content, text marshalers such as time.Time use
+// their own form, scalars as getValueString formats them in hrefs and
+// anything else printed
+func valueText(item interface{}) string {
+	if v := reflect.ValueOf(item); v.Kind() == reflect.Ptr && v.IsNil() {
+		return ""
+	}
+	if marshaler, ok := item.(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	if value := getValueString(item); value != "<invalid>" {
+		return value
+	}
+	return fmt.Sprint(item)
+}
+
+// variable names of the {name} and {?name,...} expressions left in an href
+func templateVars(href string) []string {
+	var vars	[]string
+
+	reg := regexp.MustCompile("{[^}]+}")
+	for _, part := range reg.FindAllString(href, -1) {
+		names := strings.TrimLeft(part[1:len(part) - 1], "+#./;?&")
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimRight(strings.TrimSpace(name), "*"); name != "" {
+				vars = append(vars, name)
+			}
+		}
+	}
+	return vars
+}
+
+// removes the {?name,...} and {&name,...} expressions from an href,
+// returning the href and the names of the query variables
+func splitQueryTemplate(href string) (string, []string) {
+	var vars	[]string
+
+	reg := regexp.MustCompile("{[?&][^}]*}")
+	for _, part := range reg.FindAllString(href, -1) {
+		vars = append(vars, templateVars(part)...)
+	}
+	return reg.ReplaceAllString(href, ""), vars
+}
+
+// marker fields (Entity, Link, Action, Curie) describe the hypermedia and
+// are not part of the item data
+func isHypermediaField(typ reflect.Type) bool {
+	if typ.PkgPath() != reflect.TypeOf(Entity(false)).PkgPath() {
+		return false
+	}
+
+	switch typ.Name() {
+		case "Entity", "Link", "Action", "Curie":
+			return true
+	}
+	return false
+}
+
+// With security enabled a link or action is only rendered when an access
+// rule was added for it, otherwise paths without a rule are open
+func (this *Decorator) EnableSecurity() {
+	this.reg.update(func(snap *snapshot) {
+		snap.secure = true
+	})
+}
+
+// Sets the vocabulary IRI used as @vocab in JSON-LD documents
+func (this *Decorator) SetVocabulary(iri string) {
+	this.reg.update(func(snap *snapshot) {
+		snap.vocab = iri
+	})
 }
 
-func (this Decorator) UpdatePath(path string, props map[string]interface{}) string {
+// the configured vocabulary, or one below the server prefix
+func (this *Decorator) vocabulary() string {
+	if vocab := this.registered().vocab; vocab != "" {
+		return vocab
+	}
+	return this.srvr_prefix + "/vocab#"
+}
+
+// Sets the server prefix used when Decorate is called without one
+func (this *Decorator) SetPrefix(prefix string) {
+	this.reg.update(func(snap *snapshot) {
+		snap.prefix = prefix
+	})
+}
+
+func (this *Decorator) UpdatePath(path string, props map[string]interface{}) string {
 
 	reg := regexp.MustCompile("{[^}]+}")
 	parts := reg.FindAllString(path, -1)
@@ -384,8 +737,8 @@ func (this Decorator) UpdatePath(path string, props map[string]interface{}) stri
 	return path
 }
 
-func (this Decorator) GetEntity(entName string) *entity {
-	ent, found := this.entities[entName]
+func (this *Decorator) GetEntity(entName string) *entity {
+	ent, found := this.registered().entities[entName]
 	if found {
 		return &ent
 	} else {
@@ -393,29 +746,94 @@ func (this Decorator) GetEntity(entName string) *entity {
 	}
 }
 
-func (this Decorator) AddAccess(path string, method string, scope []string) {
+// returns the data fields of the request struct registered under name,
+// prompt and default value are taken from the title and value tags
+func (this *Decorator) requestFields(name string) []formField {
+	fields := make([]formField, 0)
+
+	t, found := this.registered().requests[name]
+	if !found {
+		return fields
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || isHypermediaField(f.Type) {
+			continue
+		}
+
+		var fld	formField
+
+		ftmp := strings.Join(strings.Fields(string(f.Tag)), " ")
+		tags := reflect.StructTag(ftmp)
+
+		// the form names the keys the request body is decoded from
+		jsonName := strings.Split(tags.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+
+		fld.name = f.Name
+		if jsonName != "" {
+			fld.name = jsonName
+		}
+		fld.typ = tags.Get("type")
+		fld.title = tags.Get("title")
+		fld.value = tags.Get("value")
+
+		if fld.typ == "" {
+			fld.typ = inputType(f.Type)
+		}
+
+		fields = append(fields, fld)
+	}
+	return fields
+}
+
+// maps the go type of a request field to an html5 input type
+func inputType(typ reflect.Type) string {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.PkgPath() == "time" && typ.Name() == "Time" {
+		return "datetime-local"
+	}
+
+	switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		     reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		     reflect.Float32, reflect.Float64:
+			return "number"
+		case reflect.Bool:
+			return "checkbox"
+	}
+	return "text"
+}
+
+func (this *Decorator) AddAccess(path string, method string, scope []string) {
 	methPath := method + ":" + path
-	this.paths[methPath] = make([]string, 0)
-	this.paths[methPath] = append(this.paths[methPath], scope...)
+	access := make([]string, 0)
+	access = append(access, scope...)
+
+	this.reg.update(func(snap *snapshot) {
+		snap.paths[methPath] = access
+	})
 }
 
-func (this Decorator) hasAccess(path string, method string) bool {
-	fmt.Println("check access", path, method)
+func (this *Decorator) hasAccess(path string, method string) bool {
 	methPath := method + ":" + path
-	access, found := this.paths[methPath]
+	access, found := this.registered().paths[methPath]
 	if found {
 		for i := range access {
 			if _, hasScope := this.scopes[access[i]]; hasScope {
-				fmt.Println("  scope found")
 				return true
 			} else if access[i] == "<valid>" {
 				return true
 			}
 		}
 	} else {
-		fmt.Println("  Path not found")
-		return true
+		return !this.registered().secure
 	}
-	fmt.Println("  Scope not found")
 	return false
 }
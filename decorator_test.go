@@ -0,0 +1,186 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// run with go test -race, the tests share decorators between goroutines
+
+type testOrder struct {
+	Entity	`class:"testOrder" href:"orders/{Id}"`
+	Id	int
+	Up	Link	`rel:"up" href:"orders"`
+	Audit	Link	`rel:"audit" href:"admin/orders/{Id}"`
+}
+
+type testItem struct {
+	Entity	`class:"testItem" href:"items/{Id}"`
+	Id	int
+	Up	Link	`rel:"up" href:"items"`
+}
+
+var testFormats = []string{
+	"application/vnd.siren+json",
+	"application/hal+json",
+	"application/vnd.collection+json",
+	"application/vnd.api+json",
+	"application/ld+json",
+}
+
+func testDecorate(t *testing.T, dec *Decorator, mime string, prefix string, response interface{}, scopes []string) string {
+	body, err := json.Marshal(dec.Decorate(mime, prefix, response, scopes))
+	if err != nil {
+		t.Errorf("%s: %v", mime, err)
+	}
+	return string(body)
+}
+
+func TestConcurrentDecorateScopes(t *testing.T) {
+	dec := NewDecorator()
+	dec.RegisterEntity(&testOrder{})
+	dec.AddAccess("admin/orders/{Id}", "GET", []string{"admin"})
+
+	var wg	sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			admin := i % 2 == 0
+			scope, prefix, other := "user", "http://user.example", "http://admin.example"
+			if admin {
+				scope, prefix, other = "admin", "http://admin.example", "http://user.example"
+			}
+
+			for _, mime := range testFormats {
+				body := testDecorate(t, dec, mime, prefix, testOrder{Id: i}, []string{scope})
+				if admin != strings.Contains(body, "/admin/orders/" + strconv.Itoa(i)) {
+					t.Errorf("%s: audit link for scope %s: %s", mime, scope, body)
+				}
+				if !strings.Contains(body, prefix + "/orders") || strings.Contains(body, other) {
+					t.Errorf("%s: prefix %s not isolated: %s", mime, prefix, body)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestConcurrentRegistration(t *testing.T) {
+	dec := NewDecorator()
+	dec.RegisterEntity(&testOrder{})
+
+	var wg	sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			dec.RegisterEntity(&testItem{})
+			dec.UnregisterEntity("testItem")
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for _, mime := range testFormats {
+				body := testDecorate(t, dec, mime, "http://example", testOrder{Id: i}, nil)
+				if !strings.Contains(body, "http://example/orders") {
+					t.Errorf("%s: registered entity lost: %s", mime, body)
+				}
+
+				// the item is either fully registered or not at all
+				body = testDecorate(t, dec, mime, "http://example", testItem{Id: i}, nil)
+				if strings.Contains(body, "http://example/items/" + strconv.Itoa(i)) != strings.Contains(body, "http://example/items\"") {
+					t.Errorf("%s: partially registered entity: %s", mime, body)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if dec.GetEntity("testItem") != nil {
+		t.Errorf("testItem still registered")
+	}
+}
+
+func TestConcurrentSettings(t *testing.T) {
+	dec := NewDecorator(WithPrefix("http://default.example"))
+	dec.RegisterEntity(&testOrder{})
+
+	var wg	sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			dec.AddAccess("orders/" + strconv.Itoa(i), "GET", []string{"user"})
+			dec.SetDefaultFormat(testFormats[i % len(testFormats)])
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			body := testDecorate(t, dec, "application/hal+json", "", testOrder{Id: i}, nil)
+			if !strings.Contains(body, "http://default.example/orders") {
+				t.Errorf("default prefix not used: %s", body)
+			}
+			if _, err := dec.Negotiate("*/*"); err != nil {
+				t.Errorf("negotiate: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+type testAccount struct {
+	Entity	`class:"testAccount" href:"accounts/{Id}"`
+	Id	int
+	Owner	testItem	`rel:"owner"`
+	secret	string
+}
+
+func TestDecorateEdgeCases(t *testing.T) {
+	dec := NewDecorator()
+	dec.RegisterEntity(&testItem{})
+	dec.RegisterEntity(&testAccount{})
+
+	account := testAccount{Id: 1, Owner: testItem{Id: 2}, secret: "hidden"}
+	for mime := range dec.registered().decorators {
+		// a nil body (204 No Content), a map and unexported fields must not panic
+		dec.Decorate(mime, "", nil, nil)
+		dec.Decorate(mime, "", map[string]int{"count": 1}, nil)
+		dec.Decorate(mime, "", account, nil)
+	}
+
+	for _, mime := range []string{"application/hal+json", "application/vnd.siren+json"} {
+		body := testDecorate(t, dec, mime, "http://example", account, nil)
+		if !strings.Contains(body, "\"owner\"") || strings.Contains(body, "hidden") {
+			t.Errorf("%s: owner not embedded by rel: %s", mime, body)
+		}
+	}
+}
@@ -58,8 +58,6 @@ type HalLink struct {
 func halDecorator(response interface{}, dec *Decorator) (interface{}) {
 	var hm_resp 	HalDocument
 
-	myDec = dec
-
 	hm_resp = make(map[string]interface{}, 0)
 	v := reflect.ValueOf(response)
 	switch v.Kind() {
@@ -68,10 +66,10 @@ func halDecorator(response interface{}, dec *Decorator) (interface{}) {
 			// Any sub-entities (struct or array), placed in Embedded
 			class := reflect.TypeOf(response).Name()
 
-			props, ents := stripEmbedded(v)
+			props, ents := stripEmbedded(dec, v)
 
-			links := halResourceLinks(myDec.GetEntity(class), props, false)
-			curies := halDocumentCuries(myDec.GetEntity(class))
+			links := halResourceLinks(dec, dec.GetEntity(class), props, false)
+			curies := halDocumentCuries(dec.GetEntity(class))
 
 			for c_key, c_itm := range curies {
 				links[c_key] = c_itm
@@ -82,11 +80,11 @@ func halDecorator(response interface{}, dec *Decorator) (interface{}) {
 				hm_resp[p_key] = p_itm
 			}
 			hm_resp["_embedded"] = ents
-		case reflect.Slice, reflect.Array, reflect.Map:
+		case reflect.Slice, reflect.Array:
 			props := make(map[string]interface{})
-			resources, class := getEmbeddedList(v)
-			links := halResourceLinks(myDec.GetEntity(class), props, false)
-			curies := halDocumentCuries(myDec.GetEntity(class))
+			resources, class := getEmbeddedList(dec, v)
+			links := halResourceLinks(dec, dec.GetEntity(class), props, false)
+			curies := halDocumentCuries(dec.GetEntity(class))
 
 			for c_key, c_itm := range curies {
 				links[c_key] = c_itm
@@ -94,24 +92,65 @@ func halDecorator(response interface{}, dec *Decorator) (interface{}) {
 
 			hm_resp["_links"] = links
 			hm_resp["_embedded"] = resources
+		case reflect.Invalid:
+			// no body, e.g. 204 No Content
 		default:
 			hm_resp[reflect.TypeOf(response).Name()] = response
 	}
 
+	links, ok := hm_resp["_links"].(map[string]interface{})
+	if !ok {
+		links = make(map[string]interface{})
+		hm_resp["_links"] = links
+	}
+	if profile := dec.profileHref(); profile != "" {
+		if _, found := links["profile"]; !found {
+			links["profile"] = HalLink{Href: profile}
+		}
+	}
+
 	return hm_resp
 }
 
-func halResourceLinks(ent *entity, props map[string]interface{}, sub bool) (map[string]interface{}) {
+func halResourceLinks(dec *Decorator, ent *entity, props map[string]interface{}, sub bool) (map[string]interface{}) {
 	lnklist := make(map[string]interface{})
 
-	for _, e_lnk := range ent.links {
-		if sub && strings.IndexFunc(e_lnk.rel[:1], unicode.IsUpper) == 0 {
+	if ent == nil {
+		return lnklist
+	}
+
+	// a rel with several links, or flagged as an array, maps to a list
+	rellist := make(map[string][]HalLink)
+	relarray := make(map[string]bool)
+	relorder := make([]string, 0)
+
+	for j := 0; j < len(ent.links); j++ {
+		e_lnk := ent.links[j]
+		if !dec.hasAccess(e_lnk.href, "GET") {
 			continue
 		}
 
-		lnk := HalLink{e_lnk.href, e_lnk.templated, e_lnk.typ, "", e_lnk.name, "", e_lnk.title, ""}
-		lnk.Href = myDec.UpdatePath(lnk.Href, props)
-		lnklist[e_lnk.rel] = lnk
+		lnk := HalLink{e_lnk.href, e_lnk.templated, e_lnk.typ, e_lnk.deprecation, e_lnk.name, e_lnk.profile, e_lnk.title, e_lnk.hreflang}
+		lnk.Href = dec.UpdatePath(lnk.Href, props)
+
+		for _, rel := range splitList(e_lnk.rel) {
+			if sub && strings.IndexFunc(rel[:1], unicode.IsUpper) == 0 {
+				continue
+			}
+			if _, found := rellist[rel]; !found {
+				relorder = append(relorder, rel)
+			}
+			rellist[rel] = append(rellist[rel], lnk)
+			relarray[rel] = relarray[rel] || e_lnk.array
+		}
+	}
+
+	for _, rel := range relorder {
+		if len(rellist[rel]) == 1 && !relarray[rel] {
+			lnklist[rel] = rellist[rel][0]
+		} else {
+			lnklist[rel] = rellist[rel]
+		}
 	}
 	return lnklist
 }
@@ -119,6 +158,10 @@ func halResourceLinks(ent *entity, props map[string]interface{}, sub bool) (map[
 func halDocumentCuries(ent *entity) (map[string]interface{}) {
 	lnklist := make(map[string]interface{})
 
+	if ent == nil {
+		return lnklist
+	}
+
 	var curlist	[]HalCurie
 
 	curlist = make([]HalCurie, len(ent.curies))
@@ -134,26 +177,38 @@ func halDocumentCuries(ent *entity) (map[string]interface{}) {
 	return lnklist
 }
 
-func stripEmbedded(in reflect.Value) (map[string]interface{}, map[string]interface{}) {
+func stripEmbedded(dec *Decorator, in reflect.Value) (map[string]interface{}, map[string]interface{}) {
 	emb := make(map[string]interface{})
 	props := make(map[string]interface{})
 
 	typ := reflect.TypeOf(in.Interface())
+	parent := dec.GetEntity(typ.Name())
 	for i := 0; i < typ.NumField(); i++ {
 		vItem := in.Field(i)
+		if typ.Field(i).PkgPath != "" {
+			continue
+		}
 		switch vItem.Kind() {
 			case reflect.Slice, reflect.Array, reflect.Map:
+				if vItem.Len() == 0 || vItem.Kind() == reflect.Map {
+					props[typ.Field(i).Name] = vItem.Interface()
+					continue
+				}
 				item := vItem.Index(0)
-				if itm := myDec.GetEntity(item.Type().Name()); itm != nil {
-					resources, _ := getEmbeddedList(vItem)
-					emb[item.Type().Name()] = resources
+				if itm := dec.GetEntity(item.Type().Name()); itm != nil {
+					resources, _ := getEmbeddedList(dec, vItem)
+					emb[embeddedRel(parent, typ.Field(i).Name, itm, item.Type().Name())] = resources
 				} else {
 					props[typ.Field(i).Name] = vItem.Interface()
 				}
 			default:
-				if itm := myDec.GetEntity(typ.Field(i).Name); itm != nil {
-					resource := getEmbedded(false, vItem)
-					emb[typ.Field(i).Name] = resource
+				var itm		*entity
+				if vItem.Kind() == reflect.Struct {
+					itm = dec.GetEntity(vItem.Type().Name())
+				}
+				if itm != nil {
+					resource := getEmbedded(dec, false, vItem)
+					emb[embeddedRel(parent, typ.Field(i).Name, itm, typ.Field(i).Name)] = resource
 				} else {
 					props[typ.Field(i).Name] = vItem.Interface()
 				}
@@ -162,7 +217,7 @@ func stripEmbedded(in reflect.Value) (map[string]interface{}, map[string]interfa
 	return props, emb
 }
 
-func getEmbeddedList(val reflect.Value) ([]interface{}, string) {
+func getEmbeddedList(dec *Decorator, val reflect.Value) ([]interface{}, string) {
 	var className		string
 
 	embList := make([]interface{}, 0)
@@ -170,7 +225,7 @@ func getEmbeddedList(val reflect.Value) ([]interface{}, string) {
 	for i := 0; i < val.Len(); i++ {
 		vItem := val.Index(i)
 
-		item := getEmbedded(true, vItem)
+		item := getEmbedded(dec, true, vItem)
 
 		embList = append(embList, item)
 
@@ -182,18 +237,25 @@ func getEmbeddedList(val reflect.Value) ([]interface{}, string) {
 	return embList, className
 }
 
-func getEmbedded(embedded bool, in reflect.Value) map[string]interface{} {
+func getEmbedded(dec *Decorator, embedded bool, in reflect.Value) map[string]interface{} {
 	resp := make(map[string]interface{}, 0)
         typ := reflect.TypeOf(in.Interface())
-	if subent := myDec.GetEntity(in.Type().Name()); subent != nil {
+	if subent := dec.GetEntity(in.Type().Name()); subent != nil {
                 val := reflect.ValueOf(in.Interface())
                 for i := 0; i < typ.NumField(); i++ {
+                        if typ.Field(i).PkgPath != "" {
+                                continue
+                        }
                         valf := val.Field(i)
                         resp[typ.Field(i).Name] = valf.Interface()
                 }
 
 		// class := reflect.TypeOf(in).Name()
-		links := halResourceLinks(subent, resp, embedded)
+		links := halResourceLinks(dec, subent, resp, embedded)
+
+		if _, found := links["self"]; !found && subent.href != "" && dec.hasAccess(subent.href, "GET") {
+			links["self"] = HalLink{Href: dec.UpdatePath(subent.href, resp), Type: subent.typ}
+		}
 
 		if len(links) > 0 {
 			resp["_links"] = links
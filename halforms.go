@@ -0,0 +1,131 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"reflect"
+)
+
+type HalFormsTemplate struct {
+	Title		string			`json:"title,omitempty"`
+	Method		string			`json:"method"`
+	ContentType	string			`json:"contentType,omitempty"`
+	Target		string			`json:"target,omitempty"`
+	Properties	[]HalFormsProperty	`json:"properties"`
+}
+
+type HalFormsProperty struct {
+	Name		string			`json:"name"`
+	Prompt		string			`json:"prompt,omitempty"`
+	Type		string			`json:"type,omitempty"`
+	Value		string			`json:"value,omitempty"`
+}
+
+// This takes the data destined for the http response body and adds hypermedia content
+// to the message prior to marshaling the data and returning it to the client
+// The HalFormsDecorator renders the HAL document and adds the registered actions
+// as _templates, loosely following the HAL-FORMS specification
+// mime type: application/prs.hal-forms+json
+func halFormsDecorator(response interface{}, dec *Decorator) (interface{}) {
+	hm_resp, ok := halDecorator(response, dec).(HalDocument)
+	if !ok {
+		return hm_resp
+	}
+
+	var ent		*entity
+
+	props := make(map[string]interface{})
+	v := reflect.ValueOf(response)
+	switch v.Kind() {
+		case reflect.Struct:
+			ent = dec.GetEntity(reflect.TypeOf(response).Name())
+			for p_key, p_itm := range hm_resp {
+				if p_key != "_links" && p_key != "_embedded" {
+					props[p_key] = p_itm
+				}
+			}
+		case reflect.Slice, reflect.Array:
+			if v.Len() > 0 {
+				class := v.Index(0).Type().Name()
+				if ent = dec.GetEntity("[]" + class); ent == nil {
+					ent = dec.GetEntity(class)
+				}
+			}
+	}
+
+	if templates := halFormsTemplates(dec, ent, props); len(templates) > 0 {
+		hm_resp["_templates"] = templates
+	}
+
+	return hm_resp
+}
+
+// the first accessible action is the default template, the others are
+// keyed by action name
+func halFormsTemplates(dec *Decorator, ent *entity, props map[string]interface{}) map[string]HalFormsTemplate {
+	tmpllist := make(map[string]HalFormsTemplate)
+
+	if ent == nil {
+		return tmpllist
+	}
+
+	for j := 0; j < len(ent.actions); j++ {
+		e_act := ent.actions[j]
+		if !dec.hasAccess(e_act.href, e_act.method) {
+			continue
+		}
+
+		tmpl := HalFormsTemplate{e_act.title, e_act.method, e_act.typ, "", make([]HalFormsProperty, 0)}
+		tmpl.Target = dec.UpdatePath(e_act.href, props)
+		if tmpl.Title == "" {
+			tmpl.Title = e_act.name
+		}
+		if tmpl.ContentType == "" && e_act.fields != "" {
+			tmpl.ContentType = "application/json"
+		}
+
+		if e_act.fields != "" {
+			for _, fld := range dec.requestFields(e_act.fields) {
+				tmpl.Properties = append(tmpl.Properties, HalFormsProperty{fld.name, fld.title, fld.typ, fld.value})
+			}
+		}
+
+		if len(tmpllist) == 0 {
+			tmpllist["default"] = tmpl
+		} else {
+			tmpllist[e_act.name] = tmpl
+		}
+	}
+	return tmpllist
+}
+
+// creates a new HAL-FORMS Decorator
+func newHalFormsDecorator() *indivDec {
+	dec := new(indivDec)
+	dec.Decorate = halFormsDecorator
+	return dec
+}
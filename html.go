@@ -0,0 +1,282 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"bytes"
+	"html/template"
+	"reflect"
+	"strings"
+)
+
+type htmlResource struct {
+	Profile		string
+	Class		string
+	Title		string
+	Href		string
+	Properties	[]htmlProperty
+	Links		[]htmlLink
+	Forms		[]htmlForm
+	Resources	[]htmlResource
+}
+
+type htmlProperty struct {
+	Name		string
+	Value		interface{}
+}
+
+type htmlLink struct {
+	Rel		string
+	Href		string
+	Title		string
+}
+
+type htmlForm struct {
+	Name		string
+	Title		string
+	Method		string
+	Override	string
+	Action		string
+	Fields		[]htmlField
+}
+
+type htmlField struct {
+	Name		string
+	Type		string
+	Title		string
+	Value		string
+}
+
+const htmlPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{if .Title}}{{.Title}}{{else}}{{.Class}}{{end}}</title>{{if .Profile}}
+<link rel="profile" href="{{.Profile}}">{{end}}</head>
+<body>
+{{template "resource" .}}
+</body>
+</html>
+{{define "resource"}}<div class="{{.Class}}">
+<h1>{{if .Title}}{{.Title}}{{else}}{{.Class}}{{end}}</h1>
+{{if .Href}}<p><a href="{{.Href}}">{{.Href}}</a></p>{{end}}
+{{if .Properties}}<table>
+{{range .Properties}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Links}}<ul>
+{{range .Links}}<li><a rel="{{.Rel}}" href="{{.Href}}">{{if .Title}}{{.Title}}{{else}}{{.Rel}}{{end}}</a></li>
+{{end}}</ul>{{end}}
+{{range .Forms}}<form name="{{.Name}}" method="{{.Method}}" action="{{.Action}}">
+<fieldset><legend>{{if .Title}}{{.Title}}{{else}}{{.Name}}{{end}}</legend>
+{{if .Override}}<input type="hidden" name="_method" value="{{.Override}}">
+{{end}}{{range .Fields}}<label>{{if .Title}}{{.Title}}{{else}}{{.Name}}{{end}} <input type="{{.Type}}" name="{{.Name}}" value="{{.Value}}"></label><br>
+{{end}}<input type="submit" value="{{.Name}}">
+</fieldset></form>
+{{end}}{{range .Resources}}{{template "resource" .}}{{end}}</div>
+{{end}}`
+
+var htmlTemplate = template.Must(template.New("page").Parse(htmlPage))
+
+// This takes the data destined for the http response body and renders it
+// as a browsable html page, properties as a table, links as anchors and
+// actions as forms
+// mime type: text/html
+func htmlDecorator(response interface{}, dec *Decorator) (interface{}) {
+	var res		htmlResource
+
+	if err, ok := response.(error); ok {
+		res.Class = "error"
+		res.Properties = htmlErrorProperties(err)
+		return renderHtml(dec, res, response)
+	}
+
+	v := reflect.ValueOf(response)
+	switch v.Kind() {
+		case reflect.Struct:
+			res = getHtmlResource(dec, v)
+		case reflect.Slice, reflect.Array:
+			class := "[]" + v.Type().Elem().Name()
+			ent := dec.GetEntity(class)
+			props := make(map[string]interface{})
+
+			res.Class = class
+			if ent != nil {
+				res.Title = ent.title
+			}
+			for i := 0; i < v.Len(); i++ {
+				res.Resources = append(res.Resources, getHtmlResource(dec, v.Index(i)))
+			}
+			res.Links, res.Forms = htmlControls(dec, ent, props)
+		case reflect.Invalid:
+			// no body, e.g. 204 No Content
+		default:
+			res.Class = reflect.TypeOf(response).Name()
+			res.Properties = []htmlProperty{htmlProperty{res.Class, response}}
+	}
+
+	return renderHtml(dec, res, response)
+}
+
+// the page as a string, the undecorated response if rendering fails
+func renderHtml(dec *Decorator, res htmlResource, response interface{}) interface{} {
+	res.Profile = dec.profileHref()
+
+	var buf		bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, res); err != nil {
+		return response
+	}
+	return buf.String()
+}
+
+func htmlErrorProperties(err error) []htmlProperty {
+	hmErr := toHypermediaError(err)
+
+	props := make([]htmlProperty, 0)
+	if hmErr.Title != "" {
+		props = append(props, htmlProperty{"Title", hmErr.Title})
+	}
+	if hmErr.Code != "" {
+		props = append(props, htmlProperty{"Code", hmErr.Code})
+	}
+	props = append(props, htmlProperty{"Message", hmErr.Message})
+	return props
+}
+
+func getHtmlResource(dec *Decorator, vItem reflect.Value) htmlResource {
+	var res		htmlResource
+
+	res.Class = vItem.Type().Name()
+	if vItem.Kind() != reflect.Struct {
+		res.Properties = []htmlProperty{htmlProperty{res.Class, vItem.Interface()}}
+		return res
+	}
+
+	props := make(map[string]interface{})
+	typ := vItem.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" || isHypermediaField(f.Type) {
+			continue
+		}
+		valf := vItem.Field(i)
+		props[f.Name] = valf.Interface()
+
+		switch valf.Kind() {
+			case reflect.Slice, reflect.Array:
+				if dec.GetEntity(f.Type.Elem().Name()) != nil {
+					for j := 0; j < valf.Len(); j++ {
+						res.Resources = append(res.Resources, getHtmlResource(dec, valf.Index(j)))
+					}
+					continue
+				}
+			case reflect.Struct:
+				if dec.GetEntity(f.Type.Name()) != nil {
+					res.Resources = append(res.Resources, getHtmlResource(dec, valf))
+					continue
+				}
+		}
+		res.Properties = append(res.Properties, htmlProperty{f.Name, valf.Interface()})
+	}
+
+	ent := dec.GetEntity(typ.Name())
+	if ent != nil {
+		res.Title = ent.title
+		if ent.href != "" && dec.hasAccess(ent.href, "GET") {
+			res.Href = dec.UpdatePath(ent.href, props)
+		}
+	}
+	res.Links, res.Forms = htmlControls(dec, ent, props)
+
+	return res
+}
+
+// forms only submit GET and POST, other methods post with a _method override,
+// query template variables of the href become text inputs
+func htmlControls(dec *Decorator, ent *entity, props map[string]interface{}) ([]htmlLink, []htmlForm) {
+	lnklist := make([]htmlLink, 0)
+	frmlist := make([]htmlForm, 0)
+
+	if ent == nil {
+		return lnklist, frmlist
+	}
+
+	for j := 0; j < len(ent.links); j++ {
+		e_lnk := ent.links[j]
+		if dec.hasAccess(e_lnk.href, "GET") {
+			lnk := htmlLink{strings.Join(splitList(e_lnk.rel), " "), dec.UpdatePath(e_lnk.href, props), e_lnk.title}
+			lnklist = append(lnklist, lnk)
+		}
+	}
+
+	for j := 0; j < len(ent.actions); j++ {
+		e_act := ent.actions[j]
+		if !dec.hasAccess(e_act.href, e_act.method) {
+			continue
+		}
+
+		href, query := splitQueryTemplate(dec.UpdatePath(e_act.href, props))
+		frm := htmlForm{e_act.name, e_act.title, "POST", "", href, nil}
+		switch strings.ToUpper(e_act.method) {
+			case "GET", "":
+				frm.Method = "GET"
+			case "POST":
+			default:
+				frm.Override = strings.ToUpper(e_act.method)
+		}
+
+		if e_act.fields != "" {
+			for _, fld := range dec.requestFields(e_act.fields) {
+				if fld.typ == "checkbox" && fld.value == "" {
+					fld.value = "true"
+				}
+				frm.Fields = append(frm.Fields, htmlField{fld.name, fld.typ, fld.title, fld.value})
+			}
+		}
+
+		for _, name := range query {
+			if !htmlHasField(frm.Fields, name) {
+				frm.Fields = append(frm.Fields, htmlField{name, "text", "", ""})
+			}
+		}
+		frmlist = append(frmlist, frm)
+	}
+	return lnklist, frmlist
+}
+
+func htmlHasField(fields []htmlField, name string) bool {
+	for _, fld := range fields {
+		if fld.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// creates a new HTML Decorator
+func newHtmlDecorator() *indivDec {
+	dec := new(indivDec)
+	dec.Decorate = htmlDecorator
+	return dec
+}
@@ -0,0 +1,263 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"reflect"
+	"strconv"
+)
+
+type JsonApiDoc struct {
+	Data		interface{}			`json:"data,omitempty"`
+	Errors		[]JsonApiError			`json:"errors,omitempty"`
+	Included	[]JsonApiResource		`json:"included,omitempty"`
+	Links		map[string]interface{}		`json:"links,omitempty"`
+	Meta		map[string]interface{}		`json:"meta,omitempty"`
+}
+
+type JsonApiResource struct {
+	Type		string				`json:"type"`
+	Id		string				`json:"id,omitempty"`
+	Attributes	map[string]interface{}		`json:"attributes,omitempty"`
+	Relationships	map[string]JsonApiRelationship	`json:"relationships,omitempty"`
+	Links		map[string]interface{}		`json:"links,omitempty"`
+}
+
+// a further link sharing the rel of another, the key names the link and the
+// object carries the rel
+type JsonApiLink struct {
+	Href		string				`json:"href"`
+	Rel		string				`json:"rel"`
+	Title		string				`json:"title,omitempty"`
+}
+
+type JsonApiIdentifier struct {
+	Type		string				`json:"type"`
+	Id		string				`json:"id"`
+}
+
+type JsonApiRelationship struct {
+	Data		interface{}			`json:"data"`
+}
+
+type JsonApiError struct {
+	Code		string				`json:"code,omitempty"`
+	Title		string				`json:"title,omitempty"`
+	Detail		string				`json:"detail,omitempty"`
+}
+
+// This takes the data destined for the http response body and adds hypermedia content
+// to the message prior to marshaling the data and returning it to the client
+// The JsonApiDecorator loosely follows the JSON:API specification
+// mime type: application/vnd.api+json
+func jsonApiDecorator(response interface{}, dec *Decorator) (interface{}) {
+	var hm_resp	JsonApiDoc
+
+	if err, ok := response.(error); ok {
+		hm_resp.Errors = []JsonApiError{jsonApiError(err)}
+		return hm_resp
+	}
+
+	included := make(map[string]bool)
+
+	v := reflect.ValueOf(response)
+	switch v.Kind() {
+		case reflect.Struct:
+			// Properties - attributes of the primary resource
+			// Any sub-entities (struct or array), placed in Included
+			seedIncluded(dec, v, included)
+			res, props := getResource(dec, v, &hm_resp.Included, included)
+			hm_resp.Data = res
+			hm_resp.Links = jsonApiLinks(dec, dec.GetEntity(reflect.TypeOf(response).Name()), props)
+		case reflect.Slice, reflect.Array:
+			resList := make([]JsonApiResource, 0)
+			for i := 0; i < v.Len(); i++ {
+				seedIncluded(dec, v.Index(i), included)
+			}
+			for i := 0; i < v.Len(); i++ {
+				res, _ := getResource(dec, v.Index(i), &hm_resp.Included, included)
+				resList = append(resList, res)
+			}
+
+			class := "[]" + v.Type().Elem().Name()
+			hm_resp.Data = resList
+			hm_resp.Links = jsonApiLinks(dec, dec.GetEntity(class), make(map[string]interface{}))
+			hm_resp.Meta = map[string]interface{}{"count": v.Len()}
+		case reflect.Invalid:
+			// no body, e.g. 204 No Content
+		default:
+			hm_resp.Meta = map[string]interface{}{reflect.TypeOf(response).Name(): response}
+	}
+
+	if hm_resp.Links == nil {
+		hm_resp.Links = make(map[string]interface{})
+	}
+	if profile := dec.profileHref(); profile != "" {
+		hm_resp.Links["profile"] = profile
+	}
+
+	return hm_resp
+}
+
+func jsonApiError(err error) JsonApiError {
+	hmErr := toHypermediaError(err)
+	return JsonApiError{hmErr.Code, hmErr.Title, hmErr.Message}
+}
+
+// the first link of a rel is keyed by the rel, any further one by the rel
+// and a number
+func jsonApiLinks(dec *Decorator, ent *entity, props map[string]interface{}) map[string]interface{} {
+	lnklist := make(map[string]interface{})
+
+	if ent == nil {
+		return lnklist
+	}
+
+	for j := 0; j < len(ent.links); j++ {
+		e_lnk := ent.links[j]
+		if dec.hasAccess(e_lnk.href, "GET") {
+			href := dec.UpdatePath(e_lnk.href, props)
+			for _, rel := range splitList(e_lnk.rel) {
+				if _, found := lnklist[rel]; !found {
+					lnklist[rel] = href
+					continue
+				}
+
+				key := rel
+				for n := 2; lnklist[key] != nil; n++ {
+					key = rel + "-" + strconv.Itoa(n)
+				}
+				lnklist[key] = JsonApiLink{href, rel, e_lnk.title}
+			}
+		}
+	}
+
+	if _, found := lnklist["self"]; !found && ent.href != "" && dec.hasAccess(ent.href, "GET") {
+		lnklist["self"] = dec.UpdatePath(ent.href, props)
+	}
+	return lnklist
+}
+
+func jsonApiIdentifier(dec *Decorator, vItem reflect.Value) JsonApiIdentifier {
+	typ, id := resourceIdent(dec.GetEntity(vItem.Type().Name()), vItem)
+	return JsonApiIdentifier{typ, id}
+}
+
+// primary resources are never repeated in included
+func seedIncluded(dec *Decorator, vItem reflect.Value, included map[string]bool) {
+	if ident := jsonApiIdentifier(dec, vItem); ident.Id != "" {
+		included[ident.Type + ":" + ident.Id] = true
+	}
+}
+
+func getResource(dec *Decorator, vItem reflect.Value, incList *[]JsonApiResource, included map[string]bool) (JsonApiResource, map[string]interface{}) {
+	var res		JsonApiResource
+
+	props := make(map[string]interface{})
+	ent := dec.GetEntity(vItem.Type().Name())
+	ident := jsonApiIdentifier(dec, vItem)
+
+	res.Type = ident.Type
+	res.Id = ident.Id
+	res.Attributes = make(map[string]interface{})
+	res.Relationships = make(map[string]JsonApiRelationship)
+
+	if vItem.Kind() != reflect.Struct {
+		res.Attributes[vItem.Type().Name()] = vItem.Interface()
+		return res, props
+	}
+
+	idField := "Id"
+	if ent != nil && ent.idField != "" {
+		idField = ent.idField
+	}
+
+	typ := vItem.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		valf := vItem.Field(i)
+		if f.PkgPath != "" || isHypermediaField(f.Type) {
+			continue
+		}
+		props[f.Name] = valf.Interface()
+
+		switch valf.Kind() {
+			case reflect.Slice, reflect.Array:
+				if subent := dec.GetEntity(f.Type.Elem().Name()); subent != nil {
+					idents := make([]JsonApiIdentifier, 0)
+					for j := 0; j < valf.Len(); j++ {
+						if ident := includeResource(dec, valf.Index(j), incList, included); ident.Id != "" {
+							idents = append(idents, ident)
+						}
+					}
+					res.Relationships[embeddedRel(ent, f.Name, subent, f.Name)] = JsonApiRelationship{idents}
+					continue
+				}
+			case reflect.Struct:
+				if subent := dec.GetEntity(f.Type.Name()); subent != nil {
+					// a related resource without id is an empty to-one relationship
+					var rel		JsonApiRelationship
+					if ident := includeResource(dec, valf, incList, included); ident.Id != "" {
+						rel.Data = ident
+					}
+					res.Relationships[embeddedRel(ent, f.Name, subent, f.Name)] = rel
+					continue
+				}
+		}
+
+		if f.Name != idField {
+			res.Attributes[f.Name] = valf.Interface()
+		}
+	}
+
+	res.Links = make(map[string]interface{})
+	if ent != nil && ent.href != "" && dec.hasAccess(ent.href, "GET") {
+		res.Links["self"] = dec.UpdatePath(ent.href, props)
+	}
+
+	return res, props
+}
+
+// adds the related resource to included once, returning its linkage
+func includeResource(dec *Decorator, vItem reflect.Value, incList *[]JsonApiResource, included map[string]bool) JsonApiIdentifier {
+	ident := jsonApiIdentifier(dec, vItem)
+
+	key := ident.Type + ":" + ident.Id
+	if ident.Id != "" && !included[key] {
+		included[key] = true
+		res, _ := getResource(dec, vItem, incList, included)
+		*incList = append(*incList, res)
+	}
+	return ident
+}
+
+// creates a new JSON:API Decorator
+func newJsonApiDecorator() *indivDec {
+	dec := new(indivDec)
+	dec.Decorate = jsonApiDecorator
+	return dec
+}
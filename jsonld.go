@@ -0,0 +1,276 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"reflect"
+	"strings"
+)
+
+const hydraNamespace = "http://www.w3.org/ns/hydra/core#"
+
+// links whose rel is taken by a property are keyed by the registered IRI
+const ianaRelations = "http://www.iana.org/assignments/relation/"
+
+type LdDocument		map[string]interface{}
+
+type HydraIriTemplate struct {
+	Type		string			`json:"@type"`
+	Template	string			`json:"hydra:template"`
+	Mapping		[]HydraIriMapping	`json:"hydra:mapping"`
+}
+
+type HydraIriMapping struct {
+	Type		string		`json:"@type"`
+	Variable	string		`json:"hydra:variable"`
+	Property	string		`json:"hydra:property"`
+	Required	bool		`json:"hydra:required"`
+}
+
+type HydraOperation struct {
+	Type		string		`json:"@type"`
+	Method		string		`json:"hydra:method"`
+	Title		string		`json:"hydra:title,omitempty"`
+	Target		interface{}	`json:"hydra:target,omitempty"`
+	Expects		string		`json:"hydra:expects,omitempty"`
+	Returns		string		`json:"hydra:returns,omitempty"`
+}
+
+// This takes the data destined for the http response body and adds hypermedia content
+// to the message prior to marshaling the data and returning it to the client
+// The JsonLdDecorator emits JSON-LD nodes with operations from the Hydra vocabulary
+// mime type: application/ld+json
+func jsonLdDecorator(response interface{}, dec *Decorator) (interface{}) {
+	var hm_resp	LdDocument
+	var ctx		map[string]interface{}
+
+	v := reflect.ValueOf(response)
+	switch v.Kind() {
+		case reflect.Struct:
+			class := reflect.TypeOf(response).Name()
+			ctx = jsonLdContext(dec, dec.GetEntity(class))
+			hm_resp = getLdNode(dec, v, ctx)
+		case reflect.Slice, reflect.Array:
+			// a list is a hydra:Collection with each item as a member
+			class := "[]" + v.Type().Elem().Name()
+			ent := dec.GetEntity(class)
+			props := make(map[string]interface{})
+
+			ctx = jsonLdContext(dec, ent)
+			hm_resp = make(map[string]interface{})
+			hm_resp["@type"] = "hydra:Collection"
+			if ent != nil && ent.href != "" {
+				hm_resp["@id"] = dec.UpdatePath(ent.href, props)
+			}
+
+			members := make([]interface{}, 0)
+			for i := 0; i < v.Len(); i++ {
+				members = append(members, getLdNode(dec, v.Index(i), ctx))
+			}
+			hm_resp["hydra:member"] = members
+			hm_resp["hydra:totalItems"] = v.Len()
+
+			jsonLdLinks(dec, hm_resp, ent, props, ctx)
+			if ops := hydraOperations(dec, ent, props); len(ops) > 0 {
+				hm_resp["hydra:operation"] = ops
+			}
+		default:
+			ctx = jsonLdContext(dec, nil)
+			hm_resp = make(map[string]interface{})
+			hm_resp["@value"] = response
+	}
+
+	// a value object carries no other properties
+	if _, found := hm_resp["@value"]; !found && dec.profileHref() != "" {
+		ctx["profile"] = jsonLdIdTerm
+		hm_resp["profile"] = dec.profileHref()
+	}
+	hm_resp["@context"] = ctx
+
+	return hm_resp
+}
+
+// link terms are declared as IRI valued, class names against the vocabulary
+var jsonLdIdTerm = map[string]string{"@type": "@id"}
+var jsonLdVocabTerm = map[string]string{"@type": "@vocab"}
+
+// vocabulary for the property names, hydra prefix plus the HAL curies of
+// the entity as prefixes, link terms are added as the links are rendered
+func jsonLdContext(dec *Decorator, ent *entity) map[string]interface{} {
+	ctx := make(map[string]interface{})
+	ctx["@vocab"] = dec.vocabulary()
+	ctx["hydra"] = hydraNamespace
+	ctx["hydra:target"] = jsonLdIdTerm
+	ctx["hydra:expects"] = jsonLdVocabTerm
+	ctx["hydra:returns"] = jsonLdVocabTerm
+	ctx["hydra:property"] = jsonLdVocabTerm
+
+	if ent != nil {
+		for j := 0; j < len(ent.curies); j++ {
+			ctx[ent.curies[j].name] = strings.Replace(ent.curies[j].href, "{rel}", "", 1)
+		}
+	}
+	return ctx
+}
+
+// a node with @id and @type from the registered entity, nested entities
+// become nested nodes
+func getLdNode(dec *Decorator, vItem reflect.Value, ctx map[string]interface{}) map[string]interface{} {
+	node := make(map[string]interface{})
+
+	if vItem.Kind() != reflect.Struct {
+		node["@value"] = vItem.Interface()
+		return node
+	}
+
+	props := make(map[string]interface{})
+	typ := vItem.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" || isHypermediaField(f.Type) {
+			continue
+		}
+		valf := vItem.Field(i)
+		props[f.Name] = valf.Interface()
+
+		switch valf.Kind() {
+			case reflect.Slice, reflect.Array:
+				if dec.GetEntity(f.Type.Elem().Name()) != nil {
+					nodes := make([]interface{}, 0)
+					for j := 0; j < valf.Len(); j++ {
+						nodes = append(nodes, getLdNode(dec, valf.Index(j), ctx))
+					}
+					node[f.Name] = nodes
+					continue
+				}
+			case reflect.Struct:
+				if dec.GetEntity(f.Type.Name()) != nil {
+					node[f.Name] = getLdNode(dec, valf, ctx)
+					continue
+				}
+		}
+		node[f.Name] = valf.Interface()
+	}
+
+	ent := dec.GetEntity(typ.Name())
+	node["@type"] = typ.Name()
+	if ent != nil {
+		node["@type"] = entityKey(ent.class)
+		if ent.href != "" {
+			node["@id"] = dec.UpdatePath(ent.href, props)
+		}
+	}
+
+	jsonLdLinks(dec, node, ent, props, ctx)
+	if ops := hydraOperations(dec, ent, props); len(ops) > 0 {
+		node["hydra:operation"] = ops
+	}
+
+	return node
+}
+
+// links are IRI valued properties keyed by rel, several links of a rel are a
+// list and a rel taken by a property is keyed by its IANA IRI instead
+func jsonLdLinks(dec *Decorator, node map[string]interface{}, ent *entity, props map[string]interface{}, ctx map[string]interface{}) {
+	if ent == nil {
+		return
+	}
+
+	for j := 0; j < len(ent.links); j++ {
+		e_lnk := ent.links[j]
+		if !dec.hasAccess(e_lnk.href, "GET") {
+			continue
+		}
+
+		iri := dec.UpdatePath(e_lnk.href, props)
+		for _, rel := range splitList(e_lnk.rel) {
+			if rel == "self" {
+				continue
+			}
+
+			if _, found := props[rel]; found {
+				key := ianaRelations + rel
+				node[key] = appendLdValue(node[key], map[string]string{"@id": iri})
+				continue
+			}
+			node[rel] = appendLdValue(node[rel], iri)
+			ctx[rel] = jsonLdIdTerm
+		}
+	}
+}
+
+func appendLdValue(current interface{}, value interface{}) interface{} {
+	switch cur := current.(type) {
+		case nil:
+			return value
+		case []interface{}:
+			return append(cur, value)
+	}
+	return []interface{}{current, value}
+}
+
+func hydraOperations(dec *Decorator, ent *entity, props map[string]interface{}) []HydraOperation {
+	oplist := make([]HydraOperation, 0)
+
+	if ent == nil {
+		return oplist
+	}
+
+	for j := 0; j < len(ent.actions); j++ {
+		e_act := ent.actions[j]
+		if !dec.hasAccess(e_act.href, e_act.method) {
+			continue
+		}
+
+		op := HydraOperation{"hydra:Operation", e_act.method, e_act.title, "", e_act.fields, e_act.returns}
+		op.Target = hydraTarget(dec.UpdatePath(e_act.href, props))
+		oplist = append(oplist, op)
+	}
+	return oplist
+}
+
+// a target still holding template variables is an IriTemplate mapping each
+// variable to the property of the same name
+func hydraTarget(href string) interface{} {
+	vars := templateVars(href)
+	if len(vars) == 0 {
+		return href
+	}
+
+	tmpl := HydraIriTemplate{Type: "hydra:IriTemplate", Template: href}
+	for _, name := range vars {
+		tmpl.Mapping = append(tmpl.Mapping, HydraIriMapping{"hydra:IriTemplateMapping", name, name, false})
+	}
+	return tmpl
+}
+
+// creates a new JSON-LD Decorator
+func newJsonLdDecorator() *indivDec {
+	dec := new(indivDec)
+	dec.Decorate = jsonLdDecorator
+	return dec
+}
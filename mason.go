@@ -0,0 +1,261 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"reflect"
+	"strings"
+)
+
+type MasonDocument	map[string]interface{}
+
+type MasonControl struct {
+	Href		string			`json:"href"`
+	IsHrefTemplate	bool			`json:"isHrefTemplate,omitempty"`
+	Title		string			`json:"title,omitempty"`
+	Method		string			`json:"method,omitempty"`
+	Encoding	string			`json:"encoding,omitempty"`
+	Template	map[string]interface{}	`json:"template,omitempty"`
+	Schema		map[string]interface{}	`json:"schema,omitempty"`
+	Alt		[]MasonControl		`json:"alt,omitempty"`
+}
+
+type MasonNamespace struct {
+	Name		string			`json:"name"`
+}
+
+type MasonError struct {
+	Message		string			`json:"@message"`
+	Code		string			`json:"@code,omitempty"`
+	Details		string			`json:"@details,omitempty"`
+}
+
+// This takes the data destined for the http response body and adds hypermedia content
+// to the message prior to marshaling the data and returning it to the client
+// The MasonDecorator loosely follows the Mason specification
+// mime type: application/vnd.mason+json
+func masonDecorator(response interface{}, dec *Decorator) (interface{}) {
+	var hm_resp	MasonDocument
+
+	if err, ok := response.(error); ok {
+		hm_resp = make(map[string]interface{})
+		hm_resp["@error"] = masonError(err)
+		return hm_resp
+	}
+
+	v := reflect.ValueOf(response)
+	switch v.Kind() {
+		case reflect.Struct:
+			ent := dec.GetEntity(reflect.TypeOf(response).Name())
+			hm_resp = getMasonResource(dec, v)
+			if namespaces := masonNamespaces(ent); len(namespaces) > 0 {
+				hm_resp["@namespaces"] = namespaces
+			}
+		case reflect.Slice, reflect.Array:
+			// mason has no collection type, the list is held by an items property
+			class := "[]" + v.Type().Elem().Name()
+			ent := dec.GetEntity(class)
+			props := make(map[string]interface{})
+
+			items := make([]interface{}, 0)
+			for i := 0; i < v.Len(); i++ {
+				items = append(items, getMasonResource(dec, v.Index(i)))
+			}
+
+			hm_resp = make(map[string]interface{})
+			hm_resp["items"] = items
+			if controls := masonControls(dec, ent, props); len(controls) > 0 {
+				hm_resp["@controls"] = controls
+			}
+			if namespaces := masonNamespaces(ent); len(namespaces) > 0 {
+				hm_resp["@namespaces"] = namespaces
+			}
+		case reflect.Invalid:
+			// no body, e.g. 204 No Content
+			hm_resp = make(map[string]interface{})
+		default:
+			hm_resp = make(map[string]interface{})
+			hm_resp[reflect.TypeOf(response).Name()] = response
+	}
+
+	controls, ok := hm_resp["@controls"].(map[string]MasonControl)
+	if !ok {
+		controls = make(map[string]MasonControl)
+		hm_resp["@controls"] = controls
+	}
+	if profile := dec.profileHref(); profile != "" {
+		controls["profile"] = MasonControl{Href: profile}
+	}
+
+	return hm_resp
+}
+
+func masonError(err error) MasonError {
+	hmErr := toHypermediaError(err)
+	if hmErr.Title != "" {
+		return MasonError{hmErr.Title, hmErr.Code, hmErr.Message}
+	}
+	return MasonError{hmErr.Message, hmErr.Code, ""}
+}
+
+// the HAL curies of the entity as namespace prefixes
+func masonNamespaces(ent *entity) map[string]MasonNamespace {
+	nslist := make(map[string]MasonNamespace)
+
+	if ent == nil {
+		return nslist
+	}
+
+	for j := 0; j < len(ent.curies); j++ {
+		nslist[ent.curies[j].name] = MasonNamespace{strings.Replace(ent.curies[j].href, "{rel}", "", 1)}
+	}
+	return nslist
+}
+
+func getMasonResource(dec *Decorator, vItem reflect.Value) map[string]interface{} {
+	res := make(map[string]interface{})
+
+	if vItem.Kind() != reflect.Struct {
+		res[vItem.Type().Name()] = vItem.Interface()
+		return res
+	}
+
+	props := make(map[string]interface{})
+	typ := vItem.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" || isHypermediaField(f.Type) {
+			continue
+		}
+		valf := vItem.Field(i)
+		props[f.Name] = valf.Interface()
+
+		switch valf.Kind() {
+			case reflect.Slice, reflect.Array:
+				if dec.GetEntity(f.Type.Elem().Name()) != nil {
+					subs := make([]interface{}, 0)
+					for j := 0; j < valf.Len(); j++ {
+						subs = append(subs, getMasonResource(dec, valf.Index(j)))
+					}
+					res[f.Name] = subs
+					continue
+				}
+			case reflect.Struct:
+				if dec.GetEntity(f.Type.Name()) != nil {
+					res[f.Name] = getMasonResource(dec, valf)
+					continue
+				}
+		}
+		res[f.Name] = valf.Interface()
+	}
+
+	if controls := masonControls(dec, dec.GetEntity(typ.Name()), props); len(controls) > 0 {
+		res["@controls"] = controls
+	}
+
+	return res
+}
+
+// links keyed by rel and actions keyed by name, the request fields of an
+// action give the schema and the default values its template
+func masonControls(dec *Decorator, ent *entity, props map[string]interface{}) map[string]MasonControl {
+	ctllist := make(map[string]MasonControl)
+
+	if ent == nil {
+		return ctllist
+	}
+
+	for j := 0; j < len(ent.links); j++ {
+		e_lnk := ent.links[j]
+		if !dec.hasAccess(e_lnk.href, "GET") {
+			continue
+		}
+
+		ctl := MasonControl{Title: e_lnk.title, IsHrefTemplate: e_lnk.templated}
+		ctl.Href = dec.UpdatePath(e_lnk.href, props)
+		for _, rel := range splitList(e_lnk.rel) {
+			addMasonControl(ctllist, rel, ctl)
+		}
+	}
+
+	for j := 0; j < len(ent.actions); j++ {
+		e_act := ent.actions[j]
+		if !dec.hasAccess(e_act.href, e_act.method) {
+			continue
+		}
+
+		ctl := MasonControl{Title: e_act.title, Method: e_act.method}
+		ctl.Href = dec.UpdatePath(e_act.href, props)
+
+		if e_act.fields != "" {
+			schemaProps := make(map[string]interface{})
+			template := make(map[string]interface{})
+			for _, fld := range dec.requestFields(e_act.fields) {
+				schemaProps[fld.name] = map[string]interface{}{"type": masonSchemaType(fld.typ)}
+				if fld.value != "" {
+					template[fld.name] = fld.value
+				}
+			}
+			ctl.Schema = map[string]interface{}{"type": "object", "properties": schemaProps}
+			if len(template) > 0 {
+				ctl.Template = template
+			}
+			ctl.Encoding = "json"
+		}
+
+		addMasonControl(ctllist, e_act.name, ctl)
+	}
+	return ctllist
+}
+
+// further controls for a name already taken become alternatives of the first
+func addMasonControl(ctllist map[string]MasonControl, name string, ctl MasonControl) {
+	if first, found := ctllist[name]; found {
+		first.Alt = append(first.Alt, ctl)
+		ctllist[name] = first
+	} else {
+		ctllist[name] = ctl
+	}
+}
+
+// json schema type for an html5 input type
+func masonSchemaType(inputType string) string {
+	switch inputType {
+		case "number":
+			return "number"
+		case "checkbox":
+			return "boolean"
+	}
+	return "string"
+}
+
+// creates a new Mason Decorator
+func newMasonDecorator() *indivDec {
+	dec := new(indivDec)
+	dec.Decorate = masonDecorator
+	return dec
+}
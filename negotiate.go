@@ -0,0 +1,245 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// returned by Negotiate when no registered media type is acceptable, the
+// handler should answer 406 Not Acceptable
+var ErrNotAcceptable = errors.New("hypermedia: no acceptable media type")
+
+// one media range of an Accept header
+type mediaRange struct {
+	typ		string
+	subtype		string
+	profile		string
+	q		float64
+	order		int
+}
+
+// Picks the registered media type that best matches the Accept header and
+// returns it as the Content-Type of the response.  A type's quality comes
+// from the most specific range matching it, equal qualities go to the more
+// specific range, then to the range listed first, and the default format is
+// preferred among the types matched by the same wildcard.  A profile
+// parameter only matches the ALPS profile of the decorator and is repeated
+// in the Content-Type, other parameters are ignored.  An empty header
+// accepts anything
+func (this *Decorator) Negotiate(accept string) (string, error) {
+	return this.negotiate(accept, "")
+}
+
+// the profile is looked for below the prefix, the configured one when empty
+func (this *Decorator) negotiate(accept string, prefix string) (string, error) {
+	call := *this
+	call.snap = this.reg.load()
+	call.srvr_prefix = prefix
+	if prefix == "" {
+		call.srvr_prefix = call.snap.prefix
+	}
+
+	snap := call.snap
+	profile := call.profileHref()
+
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		ranges = []mediaRange{mediaRange{"*", "*", "", 1, 0}}
+	}
+
+	mimes := make([]string, 0, len(snap.decorators))
+	for mime := range snap.decorators {
+		mimes = append(mimes, mime)
+	}
+	sort.Strings(mimes)
+
+	best := ""
+	var bestRange	mediaRange
+	for _, mime := range mimes {
+		rng, found := matchRange(ranges, mime, profile)
+		if !found || rng.q <= 0 {
+			continue
+		}
+
+		if best == "" || betterRange(rng, bestRange, mime == snap.defaultFormat, best == snap.defaultFormat) {
+			best = mime
+			bestRange = rng
+		}
+	}
+
+	if best == "" {
+		return "", ErrNotAcceptable
+	}
+	if bestRange.profile != "" {
+		return best + ";profile=\"" + profile + "\"", nil
+	}
+	return best, nil
+}
+
+// Negotiates the media type from the Accept header and decorates the response
+// with it, returning the decorated response and the Content-Type to send.  The
+// undecorated response is returned with ErrNotAcceptable when nothing matches
+func (this *Decorator) DecorateAccept(accept string, prefix string, response interface{}, scopes []string) (interface{}, string, error) {
+	contentType, err := this.negotiate(accept, prefix)
+	if err != nil {
+		return response, "", err
+	}
+
+	mime := contentType
+	if pos := strings.Index(mime, ";"); pos > -1 {
+		mime = mime[:pos]
+	}
+	return this.Decorate(mime, prefix, response, scopes), contentType, nil
+}
+
+// Sets the media type preferred when the Accept header leaves the choice open
+func (this *Decorator) SetDefaultFormat(mime string) {
+	this.reg.update(func(snap *snapshot) {
+		snap.defaultFormat = mime
+	})
+}
+
+func betterRange(rng mediaRange, best mediaRange, isDefault bool, bestIsDefault bool) bool {
+	if rng.q != best.q {
+		return rng.q > best.q
+	}
+	if specificity(rng) != specificity(best) {
+		return specificity(rng) > specificity(best)
+	}
+	if rng.order != best.order {
+		return rng.order < best.order
+	}
+	return isDefault && !bestIsDefault
+}
+
+// a range with a profile is more specific than the bare media type
+func specificity(rng mediaRange) int {
+	if rng.typ == "*" {
+		return 0
+	} else if rng.subtype == "*" {
+		return 1
+	} else if rng.profile != "" {
+		return 3
+	}
+	return 2
+}
+
+// the most specific range matching the media type, ranges asking for a
+// profile other than the decorator's are skipped
+func matchRange(ranges []mediaRange, mime string, profile string) (mediaRange, bool) {
+	var match	mediaRange
+
+	typ, subtype := mime, ""
+	if pos := strings.Index(mime, "/"); pos > -1 {
+		typ, subtype = mime[:pos], mime[pos + 1:]
+	}
+
+	found := false
+	for _, rng := range ranges {
+		if rng.typ != "*" && rng.typ != typ {
+			continue
+		}
+		if rng.subtype != "*" && rng.subtype != subtype {
+			continue
+		}
+		if rng.profile != "" && !hasProfile(rng.profile, profile) {
+			continue
+		}
+		if !found || specificity(rng) > specificity(match) {
+			match = rng
+			found = true
+		}
+	}
+	return match, found
+}
+
+// the profile parameter holds a space separated list of URIs
+func hasProfile(profiles string, profile string) bool {
+	if profile == "" {
+		return false
+	}
+	for _, itm := range strings.Fields(profiles) {
+		if itm == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// media ranges with their q values and profile, parameters after q are
+// extensions and malformed entries are skipped
+func parseAccept(accept string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+
+	for i, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+
+		mime := strings.ToLower(strings.TrimSpace(params[0]))
+		pos := strings.Index(mime, "/")
+		if pos < 1 || pos == len(mime) - 1 {
+			continue
+		}
+
+		rng := mediaRange{mime[:pos], mime[pos + 1:], "", 1, i}
+		if rng.typ == "*" && rng.subtype != "*" {
+			continue
+		}
+
+		valid := true
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+
+			name := strings.ToLower(strings.TrimSpace(kv[0]))
+			value := strings.Trim(strings.TrimSpace(kv[1]), "\"")
+			if name == "profile" {
+				rng.profile = value
+				continue
+			} else if name != "q" {
+				continue
+			}
+
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+			}
+			rng.q = q
+			break
+		}
+
+		if valid {
+			ranges = append(ranges, rng)
+		}
+	}
+	return ranges
+}
@@ -0,0 +1,72 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	dec := NewDecorator(WithProfile(), WithPrefix("http://example"))
+
+	accepts := []struct {
+		accept		string
+		contentType	string
+	}{
+		{"", "application/vnd.siren+json"},
+		{"*/*", "application/vnd.siren+json"},
+		{"application/*", "application/vnd.siren+json"},
+		{"application/hal+json, */*", "application/hal+json"},
+		{"text/html;q=0.9, application/vnd.api+json", "application/vnd.api+json"},
+		{"application/hal+json;q=0.2, */*;q=0.1", "application/hal+json"},
+		{"application/hal+json;profile=\"http://example/profile\"", "application/hal+json;profile=\"http://example/profile\""},
+		{"application/hal+json;profile=\"http://other\", application/vnd.api+json;q=0.5", "application/vnd.api+json"},
+		{"application/json", ""},
+		{"text/*, text/html;q=0", ""},
+	}
+
+	for _, tc := range accepts {
+		contentType, err := dec.Negotiate(tc.accept)
+		if contentType != tc.contentType {
+			t.Errorf("%q: got %q, want %q", tc.accept, contentType, tc.contentType)
+		}
+		if (tc.contentType == "") != (err == ErrNotAcceptable) {
+			t.Errorf("%q: unexpected error %v", tc.accept, err)
+		}
+	}
+}
+
+func TestDefaultFormat(t *testing.T) {
+	dec := NewDecorator(WithDefaultFormat("application/hal+json"))
+
+	if contentType, _ := dec.Negotiate("application/*"); contentType != "application/hal+json" {
+		t.Errorf("default format not preferred: %q", contentType)
+	}
+	if contentType, _ := dec.Negotiate("application/vnd.siren+json, */*"); contentType != "application/vnd.siren+json" {
+		t.Errorf("default format preferred over an explicit type: %q", contentType)
+	}
+}
@@ -0,0 +1,91 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+// Configures a Decorator created by NewDecorator
+type Option func(*Decorator)
+
+// Server prefix used when Decorate is called without one
+func WithPrefix(prefix string) Option {
+	return func(dec *Decorator) {
+		dec.SetPrefix(prefix)
+	}
+}
+
+// Renders only links and actions with an access rule, see EnableSecurity
+func WithSecurity() Option {
+	return func(dec *Decorator) {
+		dec.EnableSecurity()
+	}
+}
+
+// Media type chosen by Negotiate when the Accept header leaves it open
+func WithDefaultFormat(mime string) Option {
+	return func(dec *Decorator) {
+		dec.SetDefaultFormat(mime)
+	}
+}
+
+// Vocabulary IRI the JSON-LD property names are expanded against
+func WithVocabulary(iri string) Option {
+	return func(dec *Decorator) {
+		dec.SetVocabulary(iri)
+	}
+}
+
+// Links every decorated response to the ALPS profile, see ProfileHandler
+func WithProfile() Option {
+	return func(dec *Decorator) {
+		dec.EnableProfile()
+	}
+}
+
+// Author named in Atom feeds and entries
+func WithAuthor(name string) Option {
+	return func(dec *Decorator) {
+		dec.SetAuthor(name)
+	}
+}
+
+// Limits the decorator to the listed media types, Decorate returns the
+// response undecorated for any other
+func WithFormats(mimes ...string) Option {
+	return func(dec *Decorator) {
+		keep := make(map[string]bool)
+		for _, mime := range mimes {
+			keep[mime] = true
+		}
+
+		dec.reg.update(func(snap *snapshot) {
+			for mime := range snap.decorators {
+				if !keep[mime] {
+					delete(snap.decorators, mime)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// registered entities, request structs and access rules.  A snapshot is
+// never modified once stored, writers copy it, apply their change and swap
+// the copy in, so Decorate can read without locking while classes are
+// registered or unregistered at runtime
+type registry struct {
+	mu		sync.Mutex
+	current		atomic.Value
+}
+
+type snapshot struct {
+	decorators	map[string]*indivDec
+	entities 	map[string]entity
+	requests	map[string]reflect.Type
+	paths		map[string][]string
+	prefix		string
+	secure		bool
+	defaultFormat	string
+	vocab		string
+	profile		bool
+	author		string
+}
+
+func newRegistry() *registry {
+	reg := new(registry)
+
+	snap := new(snapshot)
+	snap.decorators = make(map[string]*indivDec)
+	snap.entities = make(map[string]entity)
+	snap.requests = make(map[string]reflect.Type)
+	snap.paths = make(map[string][]string)
+
+	reg.current.Store(snap)
+	return reg
+}
+
+func (this *registry) load() *snapshot {
+	return this.current.Load().(*snapshot)
+}
+
+// writers are serialized, each works on a copy of the current snapshot
+func (this *registry) update(change func(*snapshot)) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	cur := this.load()
+
+	snap := new(snapshot)
+	snap.decorators = make(map[string]*indivDec, len(cur.decorators))
+	for mime, dec := range cur.decorators {
+		snap.decorators[mime] = dec
+	}
+	snap.entities = make(map[string]entity, len(cur.entities))
+	for key, ent := range cur.entities {
+		snap.entities[key] = ent
+	}
+	snap.requests = make(map[string]reflect.Type, len(cur.requests))
+	for key, t := range cur.requests {
+		snap.requests[key] = t
+	}
+	snap.paths = make(map[string][]string, len(cur.paths))
+	for key, scopes := range cur.paths {
+		snap.paths[key] = scopes
+	}
+	snap.prefix = cur.prefix
+	snap.secure = cur.secure
+	snap.defaultFormat = cur.defaultFormat
+	snap.vocab = cur.vocab
+	snap.profile = cur.profile
+	snap.author = cur.author
+
+	change(snap)
+
+	this.current.Store(snap)
+}
@@ -0,0 +1,192 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"reflect"
+)
+
+// A custom media type decorator.  Decorate receives the response and the
+// context of the call, which resolves registered entities with the same
+// link resolution and scope filtering used by Siren and HAL
+type Renderer interface {
+	MediaType() string
+	Decorate(response interface{}, ctx *RenderContext) interface{}
+}
+
+// read-only access to the registry for the current Decorate call
+type RenderContext struct {
+	dec		*Decorator
+}
+
+type EntityView struct {
+	Class		[]string
+	Title		string
+	Type		string
+	Href		string
+	Links		[]LinkView
+	Actions		[]ActionView
+	Curies		[]CurieView
+}
+
+type LinkView struct {
+	Name		string
+	Class		[]string
+	Rel		[]string
+	Href		string
+	Title		string
+	Type		string
+	Templated	bool
+	Deprecation	string
+	Profile		string
+	Hreflang	string
+}
+
+type ActionView struct {
+	Name		string
+	Class		[]string
+	Method		string
+	Href		string
+	Title		string
+	Type		string
+	Fields		[]FieldView
+}
+
+type FieldView struct {
+	Name		string
+	Type		string
+	Title		string
+	Value		string
+}
+
+type CurieView struct {
+	Name		string
+	Href		string
+	Templated	bool
+}
+
+// Registers a Renderer for its media type, replacing any decorator already
+// registered for it
+func (this *Decorator) RegisterHypermedia(renderer Renderer) {
+	dec := new(indivDec)
+	dec.Decorate = func(response interface{}, call *Decorator) interface{} {
+		return renderer.Decorate(response, &RenderContext{call})
+	}
+
+	this.reg.update(func(snap *snapshot) {
+		snap.decorators[renderer.MediaType()] = dec
+	})
+}
+
+// Removes the decorator for the media type, Decorate then returns the
+// response undecorated
+func (this *Decorator) UnregisterHypermedia(mime string) {
+	this.reg.update(func(snap *snapshot) {
+		delete(snap.decorators, mime)
+	})
+}
+
+// server prefix of the call
+func (this *RenderContext) Prefix() string {
+	return this.dec.srvr_prefix
+}
+
+// href with {name} segments replaced from props and the prefix added
+func (this *RenderContext) Path(href string, props map[string]interface{}) string {
+	return this.dec.UpdatePath(href, props)
+}
+
+// the struct value resolved against the entity registered for its type
+func (this *RenderContext) Resolve(value interface{}) *EntityView {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	props := make(map[string]interface{})
+	if v.Kind() == reflect.Struct {
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				props[v.Type().Field(i).Name] = v.Field(i).Interface()
+			}
+		}
+	}
+	return this.Entity(v.Type().Name(), props)
+}
+
+// the registered entity with hrefs resolved from props, links and actions
+// the caller has no access to are left out.  nil when not registered
+func (this *RenderContext) Entity(class string, props map[string]interface{}) *EntityView {
+	ent := this.dec.GetEntity(class)
+	if ent == nil {
+		return nil
+	}
+
+	view := new(EntityView)
+	view.Class = splitList(ent.class)
+	view.Title = ent.title
+	view.Type = ent.typ
+	if ent.href != "" {
+		view.Href = this.dec.UpdatePath(ent.href, props)
+	}
+
+	for j := 0; j < len(ent.links); j++ {
+		e_lnk := ent.links[j]
+		if !this.dec.hasAccess(e_lnk.href, "GET") {
+			continue
+		}
+		lnk := LinkView{e_lnk.name, splitList(e_lnk.class), splitList(e_lnk.rel), "", e_lnk.title, e_lnk.typ,
+			e_lnk.templated, e_lnk.deprecation, e_lnk.profile, e_lnk.hreflang}
+		lnk.Href = this.dec.UpdatePath(e_lnk.href, props)
+		view.Links = append(view.Links, lnk)
+	}
+
+	for j := 0; j < len(ent.actions); j++ {
+		e_act := ent.actions[j]
+		if !this.dec.hasAccess(e_act.href, e_act.method) {
+			continue
+		}
+		act := ActionView{e_act.name, splitList(e_act.class), e_act.method, "", e_act.title, e_act.typ, nil}
+		act.Href = this.dec.UpdatePath(e_act.href, props)
+		if e_act.fields != "" {
+			for _, fld := range this.dec.requestFields(e_act.fields) {
+				act.Fields = append(act.Fields, FieldView{fld.name, fld.typ, fld.title, fld.value})
+			}
+		}
+		view.Actions = append(view.Actions, act)
+	}
+
+	for j := 0; j < len(ent.curies); j++ {
+		e_cur := ent.curies[j]
+		view.Curies = append(view.Curies, CurieView{e_cur.name, e_cur.href, e_cur.templated})
+	}
+
+	return view
+}
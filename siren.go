@@ -32,7 +32,7 @@ import (
 )
 
 type Siren struct {
-	Class		string		`json:"class,omitempty"`
+	Class		[]string	`json:"class,omitempty"`
 	Title		string		`json:"title,omitempty"`
 	Properties	interface{}	`json:"properties"`
 	Entities	[]SirenEntity	`json:"entities,omitempty"`
@@ -41,20 +41,24 @@ type Siren struct {
 }
 
 type SirenEntity struct {
-	Class		string		`json:"class,omitempty"`
-	Rel		string		`json:"rel"`
-	Properties	interface{}	`json:"properties"`
+	Class		[]string	`json:"class,omitempty"`
+	Rel		[]string	`json:"rel"`
+	Title		string		`json:"title,omitempty"`
+	Href		string		`json:"href,omitempty"`
+	Type		string		`json:"type,omitempty"`
+	Properties	interface{}	`json:"properties,omitempty"`
 	Actions		[]SirenAction	`json:"actions,omitempty"`
 	Links		[]SirenLink	`json:"links,omitempty"`
 }
 
 type SirenAction struct {
 	Name		string		`json:"name"`
-	Class		string		`json:"class,omitempty"`
+	Class		[]string	`json:"class,omitempty"`
 	Method		string		`json:"method,omitempty"`
 	Href		string		`json:"href"`
 	Title		string		`json:"title,omitempty"`
 	Type		string		`json:"type,omitempty"`
+	Fields		[]Field		`json:"fields,omitempty"`
 }
 
 type Field struct {
@@ -65,55 +69,83 @@ type Field struct {
 }
 
 type SirenLink struct {
-	Class		string		`json:"class,omitempty"`
+	Class		[]string	`json:"class,omitempty"`
 	Title		string		`json:"title,omitempty"`
-	Rel		string		`json:"rel"`
+	Rel		[]string	`json:"rel"`
 	Href		string		`json:"href"`
 	Type		string		`json:"type,omitempty"`
 }
 
-var myDec *Decorator
-
 // This takes the data destined for the http response body and adds hypermedia content
 // to the message prior to marshaling the data and returning it to the client
 // The SirenDecorator loosely follows the siren specification
 // mime type: applcation/vnd.siren+json 
 func sirenDecorator(response interface{}, dec *Decorator) (interface{}) {
 	var hm_resp 	Siren
-	myDec = dec
 
 	v := reflect.ValueOf(response)
 	switch v.Kind() {
 		case reflect.Struct:
 			// Properties - not sub-entity items
 			// Any sub-entities (struct or array), placed in Entities
-			props, ents := stripSubentities(v)
+			props, ents := stripSubentities(dec, v)
+			class := reflect.TypeOf(response).Name()
 			hm_resp.Properties = props
 			hm_resp.Entities = ents
-			hm_resp.Class = reflect.TypeOf(response).Name()
-			hm_resp.Actions = sirenActions(myDec.GetEntity(hm_resp.Class), props)
-			hm_resp.Links = sirenLinks(myDec.GetEntity(hm_resp.Class), props)
-		case reflect.Slice, reflect.Array, reflect.Map:
+			hm_resp.Class = sirenClass(dec.GetEntity(class), class)
+			hm_resp.Title = sirenTitle(dec.GetEntity(class))
+			hm_resp.Actions = sirenActions(dec, dec.GetEntity(class), props)
+			hm_resp.Links = sirenLinks(dec, dec.GetEntity(class), props)
+		case reflect.Slice, reflect.Array:
 			props := make(map[string]interface{})
-			hm_resp.Entities, hm_resp.Class = getEntityList(v)
-			hm_resp.Actions = sirenActions(myDec.GetEntity(hm_resp.Class), props)
-			hm_resp.Links = sirenLinks(myDec.GetEntity(hm_resp.Class), props)
+			ents, class := getEntityList(dec, v)
+			hm_resp.Entities = ents
+			hm_resp.Class = sirenClass(dec.GetEntity(class), class)
+			hm_resp.Title = sirenTitle(dec.GetEntity(class))
+			hm_resp.Actions = sirenActions(dec, dec.GetEntity(class), props)
+			hm_resp.Links = sirenLinks(dec, dec.GetEntity(class), props)
+		case reflect.Invalid:
+			// no body, e.g. 204 No Content
 		default:
 			hm_resp.Properties = response
-			hm_resp.Class = reflect.TypeOf(response).Name()
+			hm_resp.Class = []string{reflect.TypeOf(response).Name()}
+	}
+
+	if profile := dec.profileHref(); profile != "" {
+		hm_resp.Links = append(hm_resp.Links, SirenLink{Rel: []string{"profile"}, Href: profile})
 	}
 
 	return hm_resp
 }
 
-func sirenLinks(ent *entity, props map[string]interface{}) []SirenLink {
+// classes from the registered entity, the go type name when not registered
+func sirenClass(ent *entity, name string) []string {
+	if ent != nil {
+		if list := splitList(ent.class); len(list) > 0 {
+			return list
+		}
+	}
+	if name == "" {
+		return nil
+	}
+	return []string{name}
+}
+
+func sirenTitle(ent *entity) string {
+	if ent == nil {
+		return ""
+	}
+	return ent.title
+}
+
+func sirenLinks(dec *Decorator, ent *entity, props map[string]interface{}) []SirenLink {
 	lnklist := make([]SirenLink, 0)
 	
 	if ent != nil {
 		for _, e_lnk := range ent.links {
-			if myDec.hasAccess(e_lnk.href, "GET") {
-				lnk := SirenLink{"", "", e_lnk.rel, e_lnk.href, ""}
-				lnk.Href = myDec.UpdatePath(lnk.Href, props)
+			if dec.hasAccess(e_lnk.href, "GET") {
+				lnk := SirenLink{splitList(e_lnk.class), e_lnk.title, splitList(e_lnk.rel), e_lnk.href, e_lnk.typ}
+				lnk.Href = dec.UpdatePath(lnk.Href, props)
 				lnklist = append(lnklist, lnk)
 			}
 		}
@@ -121,14 +153,14 @@ func sirenLinks(ent *entity, props map[string]interface{}) []SirenLink {
 	return lnklist
 }
 
-func sirenActions(ent *entity, props map[string]interface{}) []SirenAction {
+func sirenActions(dec *Decorator, ent *entity, props map[string]interface{}) []SirenAction {
 	actlist := make([]SirenAction, 0)
 	
 	if ent != nil {
 		for _, e_act := range ent.actions {
-			if myDec.hasAccess(e_act.href, e_act.method) {
-				act := SirenAction{e_act.name, e_act.class, e_act.method, e_act.href, "", ""}
-				act.Href = myDec.UpdatePath(act.Href, props)
+			if dec.hasAccess(e_act.href, e_act.method) {
+				act := SirenAction{e_act.name, splitList(e_act.class), e_act.method, e_act.href, e_act.title, e_act.typ, sirenFields(dec, e_act)}
+				act.Href = dec.UpdatePath(act.Href, props)
 				actlist = append(actlist, act)
 			}
 		}
@@ -136,19 +168,50 @@ func sirenActions(ent *entity, props map[string]interface{}) []SirenAction {
 	return actlist
 }
 
-func stripSubentities(in reflect.Value) (map[string]interface{}, []SirenEntity) {
+// fields of the request struct referenced by the action, the input type
+// defaults from the go type and can be set with the type tag
+func sirenFields(dec *Decorator, e_act action) []Field {
+	var fldlist	[]Field
+
+	if e_act.fields == "" {
+		return fldlist
+	}
+
+	for _, fld := range dec.requestFields(e_act.fields) {
+		fldlist = append(fldlist, Field{fld.name, fld.typ, fld.value, fld.title})
+	}
+	return fldlist
+}
+
+func stripSubentities(dec *Decorator, in reflect.Value) (map[string]interface{}, []SirenEntity) {
 	ents :=	[]SirenEntity{}
 	out := make(map[string]interface{}, 30)
 
 	typ := reflect.TypeOf(in.Interface())
+	parent := dec.GetEntity(typ.Name())
 	for i := 0; i < typ.NumField(); i++ {
 		vItem := in.Field(i)
+		if typ.Field(i).PkgPath != "" {
+			continue
+		}
+		asLink := parent != nil && parent.embedLinks[typ.Field(i).Name]
 		switch vItem.Kind() {
 			case reflect.Slice, reflect.Array, reflect.Map:
-				if vItem.Len() > 0 {
+				if vItem.Len() > 0 && vItem.Kind() != reflect.Map {
 					item := vItem.Index(0)
-					if itm := myDec.GetEntity(item.Type().Name()); itm != nil {
-						tmp, _ := getEntityList(vItem)
+					if itm := dec.GetEntity(item.Type().Name()); itm != nil && asLink {
+						rel := embeddedRel(parent, typ.Field(i).Name, itm, item.Type().Name())
+						for j := 0; j < vItem.Len(); j++ {
+							if lnk, ok := getEntityLink(dec, vItem.Index(j), rel); ok {
+								ents = append(ents, lnk)
+							}
+						}
+					} else if itm != nil {
+						rel := embeddedRel(parent, typ.Field(i).Name, itm, item.Type().Name())
+						tmp, _ := getEntityList(dec, vItem)
+						for j := range tmp {
+							tmp[j].Rel = []string{rel}
+						}
 						ents = append(ents, tmp...)
 					} else {
 						out[typ.Field(i).Name] = vItem.Interface()
@@ -157,8 +220,18 @@ func stripSubentities(in reflect.Value) (map[string]interface{}, []SirenEntity)
 					out[typ.Field(i).Name] = vItem.Interface()
 				}
 			default:
-				if itm := myDec.GetEntity(typ.Field(i).Name); itm != nil {
-					item := getEntity(false, vItem, "class")
+				var itm		*entity
+				if vItem.Kind() == reflect.Struct {
+					itm = dec.GetEntity(vItem.Type().Name())
+				}
+				if itm != nil && asLink {
+					rel := embeddedRel(parent, typ.Field(i).Name, itm, vItem.Type().Name())
+					if lnk, ok := getEntityLink(dec, vItem, rel); ok {
+						ents = append(ents, lnk)
+					}
+				} else if itm != nil {
+					item := getEntity(dec, false, vItem, "class")
+					item.Rel = []string{embeddedRel(parent, typ.Field(i).Name, itm, vItem.Type().Name())}
 					ents = append(ents, item)
 				} else {
 					out[typ.Field(i).Name] = vItem.Interface()
@@ -169,15 +242,15 @@ func stripSubentities(in reflect.Value) (map[string]interface{}, []SirenEntity)
 	return out, ents
 }
 
-func getEntityList(val reflect.Value) ([]SirenEntity, string) {
+func getEntityList(dec *Decorator, val reflect.Value) ([]SirenEntity, string) {
 	entList := []SirenEntity{}
 	var className string
 
 	for i := 0; i < val.Len(); i++ {
 		vItem := val.Index(i)
 
-		item := getEntity(true, vItem, "list")
-		item.Class = vItem.Type().Name() + " list-item"
+		item := getEntity(dec, true, vItem, "list")
+		item.Class = append(item.Class, "list-item")
 
 		entList = append(entList, item)
 
@@ -189,20 +262,25 @@ func getEntityList(val reflect.Value) ([]SirenEntity, string) {
 	return entList, className
 }
 
-func getEntity(sub bool, vItem reflect.Value, colType string) SirenEntity {
+func getEntity(dec *Decorator, sub bool, vItem reflect.Value, colType string) SirenEntity {
 	var item	SirenEntity
 
-	item.Class = vItem.Type().Name()
-	item.Rel = vItem.Type().Name()
+	item.Class = []string{vItem.Type().Name()}
+	item.Rel = []string{vItem.Type().Name()}
 	item.Properties = vItem.Interface()
 
-	if subent := myDec.GetEntity(vItem.Type().Name()); subent != nil {
+	if subent := dec.GetEntity(vItem.Type().Name()); subent != nil {
+		item.Class = sirenClass(subent, vItem.Type().Name())
+		item.Title = subent.title
+
 		typ := reflect.TypeOf(item.Properties)
 		val := reflect.ValueOf(item.Properties)
 		props := make(map[string]interface{}, typ.NumField())
 		for i := 0; i < typ.NumField(); i++ {
-			valf := val.Field(i)
-			props[typ.Field(i).Name] = valf.Interface()
+			if typ.Field(i).PkgPath != "" {
+				continue
+			}
+			props[typ.Field(i).Name] = val.Field(i).Interface()
 		}
 
 		for j:= 0; j < len(subent.links); j++ {
@@ -216,10 +294,11 @@ func getEntity(sub bool, vItem reflect.Value, colType string) SirenEntity {
 			}
 
 			if process {
-				if myDec.hasAccess(subent.links[j].href, "GET") {
-					lnk := SirenLink{"", "", subent.links[j].rel, subent.links[j].href, ""}
+				if dec.hasAccess(subent.links[j].href, "GET") {
+					e_lnk := subent.links[j]
+					lnk := SirenLink{splitList(e_lnk.class), e_lnk.title, splitList(e_lnk.rel), e_lnk.href, e_lnk.typ}
 
-					lnk.Href = myDec.UpdatePath(lnk.Href, props)
+					lnk.Href = dec.UpdatePath(lnk.Href, props)
 
 					item.Links = append(item.Links, lnk)
 				}
@@ -237,10 +316,11 @@ func getEntity(sub bool, vItem reflect.Value, colType string) SirenEntity {
 			}
 
 			if process {
-				if myDec.hasAccess(subent.actions[j].href, subent.actions[j].method) {
-					act := SirenAction{subent.actions[j].name, subent.actions[j].class, subent.actions[j].method, subent.actions[j].href, "", ""}
+				if dec.hasAccess(subent.actions[j].href, subent.actions[j].method) {
+					e_act := subent.actions[j]
+					act := SirenAction{e_act.name, splitList(e_act.class), e_act.method, e_act.href, e_act.title, e_act.typ, sirenFields(dec, e_act)}
 
-					act.Href = myDec.UpdatePath(act.Href, props)
+					act.Href = dec.UpdatePath(act.Href, props)
 
 					item.Actions = append(item.Actions, act)
 				}
@@ -250,6 +330,35 @@ func getEntity(sub bool, vItem reflect.Value, colType string) SirenEntity {
 	return item
 }
 
+// embedded link - the sub-entity by reference, href from the registered entity
+func getEntityLink(dec *Decorator, vItem reflect.Value, rel string) (SirenEntity, bool) {
+	var item	SirenEntity
+
+	subent := dec.GetEntity(vItem.Type().Name())
+	if subent == nil || subent.href == "" || !dec.hasAccess(subent.href, "GET") {
+		return item, false
+	}
+
+	props := make(map[string]interface{})
+	if vItem.Kind() == reflect.Struct {
+		typ := vItem.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			if typ.Field(i).PkgPath != "" {
+				continue
+			}
+			props[typ.Field(i).Name] = vItem.Field(i).Interface()
+		}
+	}
+
+	item.Class = sirenClass(subent, vItem.Type().Name())
+	item.Rel = []string{rel}
+	item.Title = subent.title
+	item.Href = dec.UpdatePath(subent.href, props)
+	item.Type = subent.typ
+
+	return item, true
+}
+
 func getValueString(item interface{}) string {
 	value := "<invalid>"
 	vItem := reflect.ValueOf(item)
@@ -258,7 +367,7 @@ func getValueString(item interface{}) string {
 			value = strconv.FormatInt(vItem.Int(), 10)
 		case reflect.Bool:
 			value = strconv.FormatBool(vItem.Bool())
-		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 			value = strconv.FormatUint(vItem.Uint(), 10)
 		case reflect.Float32:
 			value = strconv.FormatFloat(vItem.Float(), 'e', -1, 32)
@@ -0,0 +1,377 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type UberDoc struct {
+	Uber		UberBody	`json:"uber"`
+}
+
+type UberBody struct {
+	Version		string		`json:"version"`
+	Data		[]UberData	`json:"data,omitempty"`
+	Error		[]UberData	`json:"error,omitempty"`
+}
+
+type UberData struct {
+	Id		string		`json:"id,omitempty"`
+	Name		string		`json:"name,omitempty"`
+	Rel		[]string	`json:"rel,omitempty"`
+	Label		string		`json:"label,omitempty"`
+	Url		string		`json:"url,omitempty"`
+	Action		string		`json:"action,omitempty"`
+	Model		string		`json:"model,omitempty"`
+	Sending		string		`json:"sending,omitempty"`
+	Value		interface{}	`json:"value,omitempty"`
+	Data		[]UberData	`json:"data,omitempty"`
+}
+
+// This takes the data destined for the http response body and adds hypermedia content
+// to the message prior to marshaling the data and returning it to the client
+// The UberDecorator loosely follows the UBER document format, the result
+// marshals with encoding/json or encoding/xml
+// mime type: application/vnd.uber+json, application/vnd.uber+xml
+func uberDecorator(response interface{}, dec *Decorator) (interface{}) {
+	var hm_resp	UberDoc
+
+	hm_resp.Uber.Version = "1.0"
+
+	if err, ok := response.(error); ok {
+		hm_resp.Uber.Error = uberError(err)
+		return hm_resp
+	}
+
+	v := reflect.ValueOf(response)
+	switch v.Kind() {
+		case reflect.Struct:
+			hm_resp.Uber.Data = []UberData{getUberData(dec, v)}
+		case reflect.Slice, reflect.Array:
+			class := "[]" + v.Type().Elem().Name()
+			ent := dec.GetEntity(class)
+			props := make(map[string]interface{})
+
+			var list	UberData
+			list.Name = class
+			list.Rel = []string{"collection"}
+			if ent != nil && ent.href != "" {
+				list.Url = dec.UpdatePath(ent.href, props)
+			}
+
+			for i := 0; i < v.Len(); i++ {
+				item := getUberData(dec, v.Index(i))
+				item.Rel = append(item.Rel, "item")
+				list.Data = append(list.Data, item)
+			}
+			list.Data = append(list.Data, uberTransitions(dec, ent, props)...)
+
+			hm_resp.Uber.Data = []UberData{list}
+		case reflect.Invalid:
+			// no body, e.g. 204 No Content
+		default:
+			hm_resp.Uber.Data = []UberData{uberValue(reflect.TypeOf(response).Name(), response)}
+	}
+
+	if profile := dec.profileHref(); profile != "" {
+		hm_resp.Uber.Data = append(hm_resp.Uber.Data, UberData{Rel: []string{"profile"}, Url: profile, Action: "read"})
+	}
+
+	return hm_resp
+}
+
+func uberError(err error) []UberData {
+	errlist := make([]UberData, 0)
+
+	hmErr := toHypermediaError(err)
+	if hmErr.Title != "" {
+		errlist = append(errlist, UberData{Name: "title", Value: hmErr.Title})
+	}
+	if hmErr.Code != "" {
+		errlist = append(errlist, UberData{Name: "code", Value: hmErr.Code})
+	}
+	errlist = append(errlist, UberData{Name: "message", Value: hmErr.Message})
+
+	return errlist
+}
+
+// the entity as a data element, properties, nested entities, links and
+// actions are its children
+func getUberData(dec *Decorator, vItem reflect.Value) UberData {
+	var item	UberData
+
+	item.Name = vItem.Type().Name()
+	if vItem.Kind() != reflect.Struct {
+		return uberValue(item.Name, vItem.Interface())
+	}
+
+	props := make(map[string]interface{})
+	typ := vItem.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" || isHypermediaField(f.Type) {
+			continue
+		}
+		valf := vItem.Field(i)
+		props[f.Name] = valf.Interface()
+
+		switch valf.Kind() {
+			case reflect.Slice, reflect.Array:
+				if dec.GetEntity(f.Type.Elem().Name()) != nil {
+					sub := UberData{Name: f.Name}
+					for j := 0; j < valf.Len(); j++ {
+						sub.Data = append(sub.Data, getUberData(dec, valf.Index(j)))
+					}
+					item.Data = append(item.Data, sub)
+					continue
+				}
+			case reflect.Struct:
+				if dec.GetEntity(f.Type.Name()) != nil {
+					sub := getUberData(dec, valf)
+					sub.Name = f.Name
+					item.Data = append(item.Data, sub)
+					continue
+				}
+		}
+		item.Data = append(item.Data, uberValue(f.Name, valf.Interface()))
+	}
+
+	ent := dec.GetEntity(typ.Name())
+	if ent != nil {
+		item.Label = ent.title
+		if ent.href != "" {
+			item.Url = dec.UpdatePath(ent.href, props)
+		}
+		if typ, id := resourceIdent(ent, vItem); id != "" {
+			item.Id = typ + "-" + id
+		}
+	}
+	item.Data = append(item.Data, uberTransitions(dec, ent, props)...)
+
+	return item
+}
+
+// scalar values are the value of the data element, lists, maps and structs
+// nest a data element for each item
+func uberValue(name string, value interface{}) UberData {
+	item := UberData{Name: name}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return item
+		}
+		v = v.Elem()
+	}
+
+	if _, ok := value.(encoding.TextMarshaler); ok {
+		item.Value = value
+		return item
+	}
+
+	switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				break
+			}
+			for i := 0; i < v.Len(); i++ {
+				item.Data = append(item.Data, uberValue("", v.Index(i).Interface()))
+			}
+			return item
+		case reflect.Map:
+			items := make(map[string]interface{}, v.Len())
+			for _, key := range v.MapKeys() {
+				items[fmt.Sprint(key.Interface())] = v.MapIndex(key).Interface()
+			}
+			for _, key := range sortedKeys(items) {
+				item.Data = append(item.Data, uberValue(key, items[key]))
+			}
+			return item
+		case reflect.Struct:
+			typ := v.Type()
+			for i := 0; i < typ.NumField(); i++ {
+				f := typ.Field(i)
+				if f.PkgPath != "" || isHypermediaField(f.Type) {
+					continue
+				}
+				item.Data = append(item.Data, uberValue(f.Name, v.Field(i).Interface()))
+			}
+			return item
+		case reflect.Invalid:
+			return item
+	}
+
+	item.Value = v.Interface()
+	return item
+}
+
+func uberTransitions(dec *Decorator, ent *entity, props map[string]interface{}) []UberData {
+	trlist := make([]UberData, 0)
+
+	if ent == nil {
+		return trlist
+	}
+
+	for j := 0; j < len(ent.links); j++ {
+		e_lnk := ent.links[j]
+		if dec.hasAccess(e_lnk.href, "GET") {
+			lnk := UberData{Name: e_lnk.name, Rel: splitList(e_lnk.rel), Label: e_lnk.title, Action: "read"}
+			lnk.Url = dec.UpdatePath(e_lnk.href, props)
+			trlist = append(trlist, lnk)
+		}
+	}
+
+	for j := 0; j < len(ent.actions); j++ {
+		e_act := ent.actions[j]
+		if dec.hasAccess(e_act.href, e_act.method) {
+			act := UberData{Name: e_act.name, Rel: splitList(e_act.class), Label: e_act.title, Action: uberAction(e_act.method)}
+			act.Url = dec.UpdatePath(e_act.href, props)
+			act.Sending = e_act.typ
+			act.Model = uberModel(dec, e_act)
+			trlist = append(trlist, act)
+		}
+	}
+	return trlist
+}
+
+func uberAction(method string) string {
+	switch strings.ToUpper(method) {
+		case "POST":
+			return "append"
+		case "PATCH":
+			return "partial"
+		case "PUT":
+			return "replace"
+		case "DELETE":
+			return "remove"
+	}
+	return "read"
+}
+
+// templated body (or query string for safe actions) from the request fields
+func uberModel(dec *Decorator, e_act action) string {
+	if e_act.fields == "" {
+		return ""
+	}
+
+	parts := make([]string, 0)
+	for _, fld := range dec.requestFields(e_act.fields) {
+		parts = append(parts, fld.name + "={" + fld.name + "}")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	if uberAction(e_act.method) == "read" {
+		return "?" + strings.Join(parts, "&")
+	}
+	return strings.Join(parts, "&")
+}
+
+// uber xml - <uber version="1.0"><data .../><error>...</error></uber>
+func (this UberDoc) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "uber"}
+	start.Attr = []xml.Attr{xml.Attr{Name: xml.Name{Local: "version"}, Value: this.Uber.Version}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for i := range this.Uber.Data {
+		if err := e.Encode(this.Uber.Data[i]); err != nil {
+			return err
+		}
+	}
+
+	if len(this.Uber.Error) > 0 {
+		errStart := xml.StartElement{Name: xml.Name{Local: "error"}}
+		if err := e.EncodeToken(errStart); err != nil {
+			return err
+		}
+		for i := range this.Uber.Error {
+			if err := e.Encode(this.Uber.Error[i]); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(errStart.End()); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// data element, properties as attributes and the value as text
+func (this UberData) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "data"}
+	start.Attr = nil
+
+	attrs := []struct{ name, value string }{
+		{"id", this.Id},
+		{"name", this.Name},
+		{"rel", strings.Join(this.Rel, " ")},
+		{"label", this.Label},
+		{"url", this.Url},
+		{"action", this.Action},
+		{"model", this.Model},
+		{"sending", this.Sending},
+	}
+	for _, attr := range attrs {
+		if attr.value != "" {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attr.name}, Value: attr.value})
+		}
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if this.Value != nil {
+		if err := e.EncodeToken(xml.CharData(valueText(this.Value))); err != nil {
+			return err
+		}
+	}
+
+	for i := range this.Data {
+		if err := e.Encode(this.Data[i]); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// creates a new UBER Decorator
+func newUberDecorator() *indivDec {
+	dec := new(indivDec)
+	dec.Decorate = uberDecorator
+	return dec
+}
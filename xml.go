@@ -0,0 +1,355 @@
+//Copyright 2014  (rmullinnix@yahoo.com). All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions
+//are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in the documentation and/or other materials provided with the
+//     distribution.
+//
+//THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+//IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+//OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+//IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+//SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+//PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS;
+//OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+//WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR
+//OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF
+//ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+
+package hypermedia
+
+import (
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hal+xml - the resource element carries the self link as href, links
+// are link elements and embedded resources are nested resource elements
+func (this HalDocument) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return halXmlResource(e, this, "")
+}
+
+func halXmlResource(e *xml.Encoder, res map[string]interface{}, rel string) error {
+	start := xml.StartElement{Name: xml.Name{Local: "resource"}}
+	if rel != "" {
+		start.Attr = append(start.Attr, xmlAttr("rel", rel))
+	}
+
+	links, _ := res["_links"].(map[string]interface{})
+	if self, ok := links["self"].(HalLink); ok {
+		start.Attr = append(start.Attr, xmlAttr("href", self.Href))
+	}
+	if curies, ok := links["curies"].([]HalCurie); ok {
+		for _, cur := range curies {
+			start.Attr = append(start.Attr, xmlAttr("xmlns:" + cur.Name, cur.Href))
+		}
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(links) {
+		if key == "self" || key == "curies" {
+			continue
+		}
+
+		var lnks	[]HalLink
+		switch lnk := links[key].(type) {
+			case HalLink:
+				lnks = []HalLink{lnk}
+			case []HalLink:
+				lnks = lnk
+		}
+		for _, lnk := range lnks {
+			if err := halXmlLink(e, key, lnk); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(res) {
+		if key == "_links" || key == "_embedded" {
+			continue
+		}
+		if err := xmlProperty(e, key, res[key]); err != nil {
+			return err
+		}
+	}
+
+	switch emb := res["_embedded"].(type) {
+		case map[string]interface{}:
+			for _, key := range sortedKeys(emb) {
+				if err := halXmlEmbedded(e, key, emb[key]); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			if err := halXmlEmbedded(e, "item", emb); err != nil {
+				return err
+			}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+func halXmlEmbedded(e *xml.Encoder, rel string, emb interface{}) error {
+	switch res := emb.(type) {
+		case map[string]interface{}:
+			return halXmlResource(e, res, rel)
+		case []interface{}:
+			for i := range res {
+				if err := halXmlEmbedded(e, rel, res[i]); err != nil {
+					return err
+				}
+			}
+	}
+	return nil
+}
+
+func halXmlLink(e *xml.Encoder, rel string, lnk HalLink) error {
+	start := xml.StartElement{Name: xml.Name{Local: "link"}}
+	start.Attr = append(start.Attr, xmlAttr("rel", rel), xmlAttr("href", lnk.Href))
+
+	attrs := []xml.Attr{
+		xmlAttr("type", lnk.Type),
+		xmlAttr("deprecation", lnk.Deprecation),
+		xmlAttr("name", lnk.Name),
+		xmlAttr("profile", lnk.Profile),
+		xmlAttr("title", lnk.Title),
+		xmlAttr("hreflang", lnk.Hreflang),
+	}
+	if lnk.Templated {
+		attrs = append(attrs, xmlAttr("templated", "true"))
+	}
+	for _, attr := range attrs {
+		if attr.Value != "" {
+			start.Attr = append(start.Attr, attr)
+		}
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// siren xml - entity element with class, href and title attributes, the
+// properties, sub-entities, actions with their fields and links as children
+func (this Siren) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "entity"}}
+	start.Attr = sirenXmlAttrs(this.Class, nil, "", this.Title, "")
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if err := sirenXmlBody(e, this.Properties, this.Entities, this.Actions, this.Links); err != nil {
+		return err
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+func (this SirenEntity) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "entity"}}
+	start.Attr = sirenXmlAttrs(this.Class, this.Rel, this.Href, this.Title, this.Type)
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if err := sirenXmlBody(e, this.Properties, nil, this.Actions, this.Links); err != nil {
+		return err
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+func sirenXmlAttrs(class []string, rel []string, href string, title string, typ string) []xml.Attr {
+	attrs := make([]xml.Attr, 0)
+
+	for _, attr := range []xml.Attr{
+		xmlAttr("class", strings.Join(class, " ")),
+		xmlAttr("rel", strings.Join(rel, " ")),
+		xmlAttr("href", href),
+		xmlAttr("title", title),
+		xmlAttr("type", typ),
+	} {
+		if attr.Value != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+func sirenXmlBody(e *xml.Encoder, properties interface{}, entities []SirenEntity, actions []SirenAction, links []SirenLink) error {
+	if properties != nil {
+		if err := xmlProperty(e, "properties", properties); err != nil {
+			return err
+		}
+	}
+
+	for i := range entities {
+		if err := e.Encode(entities[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, act := range actions {
+		start := xml.StartElement{Name: xml.Name{Local: "action"}}
+		start.Attr = append(start.Attr, xmlAttr("name", act.Name))
+		for _, attr := range []xml.Attr{
+			xmlAttr("class", strings.Join(act.Class, " ")),
+			xmlAttr("method", act.Method),
+			xmlAttr("href", act.Href),
+			xmlAttr("title", act.Title),
+			xmlAttr("type", act.Type),
+		} {
+			if attr.Value != "" {
+				start.Attr = append(start.Attr, attr)
+			}
+		}
+
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, fld := range act.Fields {
+			fldStart := xml.StartElement{Name: xml.Name{Local: "field"}}
+			fldStart.Attr = append(fldStart.Attr, xmlAttr("name", fld.Name), xmlAttr("type", fld.Type))
+			if fld.Value != "" {
+				fldStart.Attr = append(fldStart.Attr, xmlAttr("value", fld.Value))
+			}
+			if fld.Title != "" {
+				fldStart.Attr = append(fldStart.Attr, xmlAttr("title", fld.Title))
+			}
+			if err := e.EncodeToken(fldStart); err != nil {
+				return err
+			}
+			if err := e.EncodeToken(fldStart.End()); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(start.End()); err != nil {
+			return err
+		}
+	}
+
+	for _, lnk := range links {
+		start := xml.StartElement{Name: xml.Name{Local: "link"}}
+		start.Attr = sirenXmlAttrs(lnk.Class, lnk.Rel, lnk.Href, lnk.Title, lnk.Type)
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(start.End()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// a property as an element named by its key, maps and structs nest their
+// fields, lists repeat the element, values that marshal themselves (such
+// as time.Time) keep their own form
+func xmlProperty(e *xml.Encoder, name string, value interface{}) error {
+	if value == nil || isHypermediaField(reflect.TypeOf(value)) {
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	switch value.(type) {
+		case xml.Marshaler:
+			return e.EncodeElement(value, start)
+		case encoding.TextMarshaler:
+			return e.EncodeElement(valueText(value), start)
+	}
+
+	switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				break
+			}
+			for i := 0; i < v.Len(); i++ {
+				if err := xmlProperty(e, name, v.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch v.Kind() {
+		case reflect.Map:
+			keys := make([]string, 0, v.Len())
+			items := make(map[string]interface{}, v.Len())
+			for _, key := range v.MapKeys() {
+				strKey := fmt.Sprint(key.Interface())
+				keys = append(keys, strKey)
+				items[strKey] = v.MapIndex(key).Interface()
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if err := xmlProperty(e, key, items[key]); err != nil {
+					return err
+				}
+			}
+		case reflect.Struct:
+			typ := v.Type()
+			for i := 0; i < typ.NumField(); i++ {
+				f := typ.Field(i)
+				if f.PkgPath != "" || isHypermediaField(f.Type) {
+					continue
+				}
+				if err := xmlProperty(e, f.Name, v.Field(i).Interface()); err != nil {
+					return err
+				}
+			}
+		case reflect.Float32, reflect.Float64:
+			if err := e.EncodeToken(xml.CharData(strconv.FormatFloat(v.Float(), 'f', -1, 64))); err != nil {
+				return err
+			}
+		default:
+			if err := e.EncodeToken(xml.CharData(fmt.Sprint(v.Interface()))); err != nil {
+				return err
+			}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+func xmlAttr(name string, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
+func sortedKeys(items map[string]interface{}) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}